@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/dan-frohlich/tabetopevents/internal/gateway/tte"
+)
+
+func (a *app) cacheCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing cache command\n%s", usage)
+	}
+	policies := tte.DefaultCachePolicies
+	entries, err := tte.CacheEntries(a.root, policies)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		fmt.Printf("%-11s %9s %10s %10s  %-7s %s\n", "KIND", "SIZE", "AGE", "TTL", "STATE", "CONVENTION / KEY")
+		for _, e := range entries {
+			state := "fresh"
+			if e.Expired() {
+				state = "expired"
+			}
+			what := e.Convention
+			if len(what) == 0 {
+				what = e.Key.Kind
+			}
+			fmt.Printf("%-11s %9s %10s %10s  %-7s %s\n", e.Key.ID, byteSize(e.Size), e.Age, e.TTL, state, what)
+		}
+	case "stats":
+		fmt.Printf("%-11s %6s %8s %10s %10s %10s  %s\n", "KIND", "COUNT", "EXPIRED", "SIZE", "NEWEST", "OLDEST", "POLICY")
+		for _, st := range tte.Stats(entries) {
+			fmt.Printf("%-11s %6d %8d %10s %10s %10s  %s\n", st.Kind, st.Count, st.Expired, byteSize(st.Size), st.Newest, st.Oldest, policies[st.Kind].Description)
+		}
+	case "prune":
+		var pruned int
+		for _, e := range entries {
+			if !e.Expired() {
+				continue
+			}
+			if err = a.root.Delete(e.Key.ID, e.Key.Kind, e.Key.DataType); err != nil {
+				return err
+			}
+			pruned++
+		}
+		a.log.Info("pruned expired cache records", "count", pruned)
+	case "clear":
+		var kinds []string
+		if len(args) > 1 {
+			if _, ok := policies[args[1]]; !ok {
+				return fmt.Errorf("unknown cache kind %q", args[1])
+			}
+			kinds = args[1:2]
+		}
+		var cleared int
+		for _, e := range entries {
+			if len(kinds) > 0 && !slices.Contains(kinds, e.Key.ID) {
+				continue
+			}
+			if err = a.root.Delete(e.Key.ID, e.Key.Kind, e.Key.DataType); err != nil {
+				return err
+			}
+			cleared++
+		}
+		a.log.Info("cleared cache records", "count", cleared)
+	default:
+		return fmt.Errorf("unknown cache command %q\n%s", args[0], usage)
+	}
+	return nil
+}
+
+func byteSize(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dan-frohlich/tabetopevents/internal/gateway/tte"
+)
+
+const usage = `usage: buddy [-v] [--encrypt] [--offline] [--tz ZONE] [--query QUERY] [--profile NAME] [command]
+
+with no command buddy browses convention events.
+
+--profile picks the tabletop.events account to use (default: TTE_PROFILE,
+or the default profile). Each profile has its own api key, session and
+likes; convention and event caches are shared.
+
+--encrypt keeps the api key and session encrypted with a passphrase, read
+from TTE_PASSPHRASE or asked for.
+
+--tz names the time zone the convention runs in, e.g. America/New_York
+(default: the local one). tabletop.events gives event times without one.
+
+--query filters the events browsed, and can be changed in the filter form:
+  type:RPG day:friday gm:"smith" duration<=240 seats>0 -cancelled
+  (publisher:wizkids OR complexity:light)
+terms are ANDed unless joined by OR; NOT or - negates one. field:value
+matches text containing value, = and != match it exactly, and number
+fields (duration, seats, tickets, sold, waiting, price, number, priority)
+and start (a time of day) compare with = != < <= > >=. Flags: cancelled,
+soldout, liked, tournament, online. Other words search names and
+descriptions.
+
+--offline browses, filters and likes cached events without logging in;
+buddy also goes offline when tabletop.events cannot be reached. Refreshing
+and the session commands are disabled offline.
+
+commands:
+  session status           show the cached tabletop.events session
+  session logout [--forget-key]
+                           end the session, optionally forgetting the api key
+  session switch           end the session and log in as another user
+  profile list             list the profiles that have been used
+  cache list               list cached records with their size and age
+  cache stats              summarize the cache per kind
+  cache prune              remove records past their freshness
+  cache clear [KIND]       remove every cached record, or those of KIND
+  diff CONVENTION [OLD [NEW]] [--liked]
+                           show how a convention's events changed between
+                           two snapshots, by default the latest two
+  itinerary CONVENTION [--start 8:00] [--end 23:00] [--break 15m] [--max-hours 8]
+                           plan the liked events of a convention that fit
+                           together with the highest total priority, and
+                           say why the others were dropped
+  watch CONVENTION [--every 5m] [--nearly-full 2] [--bell] [--desktop] [--hook CMD]
+                           poll liked events and alert when a sold out one
+                           gains seats or one is nearly full, by terminal
+                           bell (default), desktop notification or a shell
+                           command given the alert in TTE_ALERT,
+                           TTE_EVENT_NAME, TTE_EVENT_URL, TTE_SEATS_LEFT...`
+
+func (a *app) runCommand(args []string) error {
+	switch args[0] {
+	case "session":
+		return a.sessionCommand(args[1:])
+	case "profile":
+		return a.profileCommand(args[1:])
+	case "cache":
+		return a.cacheCommand(args[1:])
+	case "diff":
+		return a.diffCommand(args[1:])
+	case "itinerary":
+		return a.itineraryCommand(args[1:])
+	case "watch":
+		return a.watchCommand(args[1:])
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+}
+
+func (a *app) sessionCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing session command\n%s", usage)
+	}
+	if a.offline {
+		return fmt.Errorf("session %s: %w", args[0], tte.ErrOffline)
+	}
+	opts, err := a.clientOptions()
+	if err != nil {
+		return err
+	}
+	c, err := tte.RestoreClient(a.log, a.root, opts...)
+	if err != nil {
+		return fmt.Errorf("no cached api key, run buddy to log in: %w", err)
+	}
+
+	switch args[0] {
+	case "status":
+		return a.sessionStatus(c)
+	case "logout":
+		var forgetKey bool
+		for _, arg := range args[1:] {
+			switch arg {
+			case "--forget-key":
+				forgetKey = true
+			default:
+				return fmt.Errorf("unknown logout flag %q", arg)
+			}
+		}
+		return a.logout(c, forgetKey)
+	case "switch":
+		if err = a.logout(c, false); err != nil {
+			return err
+		}
+		var username, password string
+		username, password, err = a.credentials(a.ctx)
+		if err != nil {
+			return err
+		}
+		var s tte.Session
+		s, err = c.NewSessionContext(a.ctx, username, password)
+		if err != nil {
+			return err
+		}
+		a.log.Info("switched session", "username", s.UserName(), "session_id", s.ID())
+		return nil
+	}
+	return fmt.Errorf("unknown session command %q\n%s", args[0], usage)
+}
+
+func (a *app) sessionStatus(c tte.Client) error {
+	st, err := c.SessionStatusContext(a.ctx)
+	if err != nil {
+		return fmt.Errorf("no cached session: %w", err)
+	}
+	user := st.UserName
+	if len(user) == 0 {
+		user = "(unknown)"
+	}
+	valid := "yes"
+	if !st.Valid {
+		valid = fmt.Sprintf("no (%s)", st.Err)
+	}
+	fmt.Println(strings.Join([]string{
+		fmt.Sprintf("%12s: %s", "profile", c.Profile()),
+		fmt.Sprintf("%12s: %s", "user", user),
+		fmt.Sprintf("%12s: %s", "user id", st.UID),
+		fmt.Sprintf("%12s: %s", "session id", st.ID),
+		fmt.Sprintf("%12s: %s", "age", st.Age.Truncate(time.Second)),
+		fmt.Sprintf("%12s: %s", "valid", valid),
+	}, "\n"))
+	return nil
+}
+
+func (a *app) logout(c tte.Client, forgetKey bool) error {
+	s, err := c.CachedSession()
+	if err != nil {
+		a.log.Info("no cached session to log out of")
+		if forgetKey {
+			return c.ForgetAPIKey()
+		}
+		return nil
+	}
+	return s.LogoutContext(a.ctx, forgetKey)
+}
+
+func (a *app) profileCommand(args []string) error {
+	if len(args) == 0 || args[0] != "list" {
+		return fmt.Errorf("unknown profile command\n%s", usage)
+	}
+	names, err := tte.Profiles(a.root)
+	if err != nil {
+		return err
+	}
+	current := a.profile
+	if len(current) == 0 {
+		current = tte.DefaultProfile
+	}
+	for _, name := range names {
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+		fmt.Println(marker, name)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dan-frohlich/tabetopevents/internal/gateway/tte"
+)
+
+func (a *app) diffCommand(args []string) error {
+	var liked bool
+	var rest []string
+	for _, arg := range args {
+		switch arg {
+		case "--liked":
+			liked = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if len(rest) == 0 || len(rest) > 3 {
+		return fmt.Errorf("diff needs a convention and up to two snapshot times\n%s", usage)
+	}
+	con, err := a.findConvention(rest[0])
+	if err != nil {
+		return err
+	}
+	a.con = con
+
+	taken, err := tte.Snapshots(a.root, con)
+	if err != nil {
+		return err
+	}
+	var older, newer time.Time
+	if len(taken) > 1 {
+		older = taken[len(taken)-2]
+	}
+	if len(taken) > 0 {
+		newer = taken[len(taken)-1]
+	}
+	if len(rest) > 1 {
+		if older, err = tte.ParseSnapshotTime(rest[1]); err != nil {
+			return err
+		}
+	}
+	if len(rest) > 2 {
+		if newer, err = tte.ParseSnapshotTime(rest[2]); err != nil {
+			return err
+		}
+	}
+
+	if older.IsZero() || newer.IsZero() {
+		return fmt.Errorf("%s has %d snapshot(s), browse it again to take another", con.Name, len(taken))
+	}
+	changes, err := a.diffSnapshots(con, older, newer)
+	if err != nil {
+		return err
+	}
+	if liked {
+		a.readLikesFromCache()
+		changes = changes.Affecting(a.likes.URIs()...)
+	}
+
+	fmt.Printf("%s: %s -> %s\n", con.Name, tte.FormatSnapshotTime(older), tte.FormatSnapshotTime(newer))
+	if len(changes) == 0 {
+		fmt.Println("no changes")
+	}
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	fmt.Printf("\nsnapshots:\n")
+	for _, t := range taken {
+		fmt.Printf("  %s  %s\n", tte.FormatSnapshotTime(t), t.Local().Format(time.DateTime))
+	}
+	return nil
+}
+
+func (a *app) diffSnapshots(con tte.Convention, older time.Time, newer time.Time) (changes tte.EventChanges, err error) {
+	var before, after []tte.ConventionEvent
+	if before, err = tte.ReadSnapshot(a.root, con, older); err != nil {
+		return changes, err
+	}
+	if after, err = tte.ReadSnapshot(a.root, con, newer); err != nil {
+		return changes, err
+	}
+	return tte.DiffEvents(before, after), nil
+}
+
+// findConvention picks a cached convention by id, view uri or a unique part
+// of its name.
+func (a *app) findConvention(name string) (con tte.Convention, err error) {
+	cz, err := tte.CachedConventions(a.root)
+	if err != nil {
+		return con, fmt.Errorf("no cached conventions, run buddy to fetch them: %w", err)
+	}
+	var matches []tte.Convention
+	for _, c := range cz {
+		if c.ID == name || c.ViewURI == name {
+			return c, nil
+		}
+		if strings.Contains(strings.ToLower(c.Name), strings.ToLower(name)) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return con, fmt.Errorf("no cached convention matches %q", name)
+	case 1:
+		return matches[0], nil
+	}
+	var names []string
+	for _, c := range matches {
+		names = append(names, c.Name)
+	}
+	return con, fmt.Errorf("%q matches %d conventions: %s", name, len(matches), strings.Join(names, ", "))
+}
+
+// showLikedChanges prints what changed about liked events between the
+// snapshot seen on the last run and the latest one.
+func (a *app) showLikedChanges() {
+	taken, err := tte.Snapshots(a.root, a.con)
+	if err != nil || len(taken) == 0 {
+		return
+	}
+	latest := taken[len(taken)-1]
+	defer a.db.Store("seen", a.con.ViewURI, "txt", []byte(tte.FormatSnapshotTime(latest)))
+
+	b, err := a.db.Read("seen", a.con.ViewURI, "txt")
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			a.log.Error("failed to read the last seen snapshot", "error", err)
+		}
+		return
+	}
+	seen, err := tte.ParseSnapshotTime(string(b))
+	if err != nil || !seen.Before(latest) {
+		return
+	}
+	// the seen snapshot may have been pruned since; compare with the oldest
+	if seen.Before(taken[0]) {
+		seen = taken[0]
+	}
+
+	changes, err := a.diffSnapshots(a.con, seen, latest)
+	if err != nil {
+		a.log.Error("failed to compare snapshots", "error", err)
+		return
+	}
+	changes = changes.Affecting(a.likes.URIs()...)
+	if len(changes) == 0 {
+		return
+	}
+	out := fmt.Sprintf("what changed since last time [%s]\n", seen.Local().Format(time.DateTime))
+	for _, c := range changes {
+		out += c.String() + "\n"
+	}
+	println(lipgloss.NewStyle().Border(lipgloss.RoundedBorder(), true).Render(out[:len(out)-1]))
+}
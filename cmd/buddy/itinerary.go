@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dan-frohlich/tabetopevents/internal/gateway/tte"
+	"github.com/dan-frohlich/tabetopevents/internal/logging"
+)
+
+// defaultItineraryConstraints keep itineraries to waking hours with time to
+// get between rooms.
+var defaultItineraryConstraints = tte.ItineraryConstraints{
+	DayStart: 8 * time.Hour,
+	MinBreak: 15 * time.Minute,
+}
+
+func (a *app) itineraryCommand(args []string) (err error) {
+	c := defaultItineraryConstraints
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var value string
+		if name, v, ok := strings.Cut(arg, "="); ok && strings.HasPrefix(arg, "--") {
+			arg, value = name, v
+		} else if strings.HasPrefix(arg, "--") && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		switch arg {
+		case "--start":
+			c.DayStart, err = tte.ParseClock(value)
+		case "--end":
+			c.DayEnd, err = tte.ParseClock(value)
+		case "--break":
+			c.MinBreak, err = time.ParseDuration(value)
+		case "--max-hours":
+			var h float64
+			h, err = strconv.ParseFloat(value, 64)
+			c.MaxPerDay = time.Duration(h * float64(time.Hour))
+		default:
+			if strings.HasPrefix(arg, "--") {
+				return fmt.Errorf("unknown itinerary option %q\n%s", arg, usage)
+			}
+			rest = append(rest, arg)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", arg, err)
+		}
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("itinerary needs a convention\n%s", usage)
+	}
+	if a.con, err = a.findConvention(rest[0]); err != nil {
+		return err
+	}
+
+	// only cached events are planned with, so no session is needed
+	a.s = tte.NewOfflineClient(a.log, a.root, tte.WithProfile(a.profile), tte.WithTimeZone(a.location)).OfflineSession()
+	cache, err := a.s.GetCachedConventionEvents(a.con)
+	if err != nil {
+		return fmt.Errorf("no cached events for %s, browse it to fetch them: %w", a.con.Name, err)
+	}
+	evz := cache.ConventionEvents
+	if a.times, err = a.s.TimetableContext(a.ctx, a.con, evz); err != nil {
+		a.log.Warn("no cached dayparts, event times may be missing", "error", err)
+	}
+	a.readLikesFromCache()
+	if len(a.likes) == 0 {
+		return fmt.Errorf("no liked events for %s, browse it to like some", a.con.Name)
+	}
+	a.printItinerary(tte.BuildItinerary(evz, a.likes, a.times, c))
+	return nil
+}
+
+// printItinerary shows the scheduled events by day, then the dropped ones
+// and why.
+func (a *app) printItinerary(it tte.Itinerary) {
+	out := fmt.Sprintf("itinerary: %d event(s), total priority %d\n", len(it.Events), it.Priority)
+	var day string
+	for _, e := range it.Events {
+		t, _ := a.times.Get(e)
+		if d := t.Start.Format("Monday Jan 2"); d != day {
+			day = d
+			out += "\n" + day + "\n"
+		}
+		like, _ := a.likes.Get(e.ViewURI)
+		out += fmt.Sprintf("  %s - %s  %4d - %s (priority %d)\n", t.Start.Format("3:04 PM"), t.End.Format("3:04 PM"), e.EventNumber, e.Name, like.Priority)
+	}
+	println(lipgloss.NewStyle().Border(lipgloss.RoundedBorder(), true).Render(strings.TrimSuffix(out, "\n")))
+	if !it.Complete {
+		a.log.Warn("the search ran out of time, a better itinerary may exist", "likes", len(it.Events)+len(it.Dropped))
+	}
+
+	if len(it.Dropped) == 0 {
+		return
+	}
+	out = logging.WarnStyle.Style.Bold(true).Render(fmt.Sprintf("%d liked event(s) dropped", len(it.Dropped))) + "\n"
+	for _, d := range it.Dropped {
+		like, _ := a.likes.Get(d.Event.ViewURI)
+		out += fmt.Sprintf("  %4d - %s (priority %d): %s\n", d.Event.EventNumber, d.Event.Name, like.Priority, d.Reason)
+	}
+	println(lipgloss.NewStyle().Border(lipgloss.RoundedBorder(), true).Render(strings.TrimSuffix(out, "\n")))
+}
+
+// syncLikes makes the events at uris the liked ones, asks for the priority
+// and group of those newly liked and stores them.
+func (a *app) syncLikes(uris []string, evz []tte.ConventionEvent) {
+	var added []string
+	a.likes, added = a.likes.Sync(uris)
+
+	names := make(map[string]string, len(added))
+	for _, e := range evz {
+		names[e.ViewURI] = fmt.Sprintf("%d - %s", e.EventNumber, e.Name)
+	}
+	var priorities []huh.Option[int]
+	for p := tte.MaxPriority; p >= tte.MinPriority; p-- {
+		priorities = append(priorities, huh.NewOption(strconv.Itoa(p), p))
+	}
+	for _, uri := range added {
+		like, _ := a.likes.Get(uri)
+		err := huh.NewForm(huh.NewGroup(
+			huh.NewSelect[int]().
+				Title(fmt.Sprintf("how much do you want to play %s?", names[uri])).
+				Description("the itinerary favors higher priorities").
+				Options(priorities...).
+				Value(&like.Priority),
+			huh.NewInput().
+				Title("alternative group (optional)").
+				Description("events sharing a group are interchangeable, e.g. sessions of one game; an itinerary holds one").
+				Value(&like.Group),
+		)).WithTheme(huh.ThemeBase16()).Run()
+		if err != nil {
+			break
+		}
+		like.Group = strings.TrimSpace(like.Group)
+		a.likes.Update(like)
+	}
+	if a.likesUnreadable {
+		a.log.Warn("not saving liked events over ones that could not be read")
+		return
+	}
+	if err := tte.StoreLikes(a.db, a.con, a.likes); err != nil {
+		a.log.Error("failed to store liked events", "error", err)
+	}
+}
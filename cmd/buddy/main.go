@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
+	"os/signal"
 	"sort"
 	"strings"
 	"time"
@@ -19,7 +23,72 @@ import (
 
 func main() {
 	log := logging.Log{Level: logging.LogLevelInfo}
-	a := &app{log: log, db: tte.NewDB(log)}
+	var (
+		args    []string
+		encrypt bool
+		offline bool
+		tz      string
+		query   string
+		profile = os.Getenv(profileEnv)
+	)
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		switch {
+		case arg == "-v", arg == "--verbose":
+			log.Level = logging.LogLevelDebug
+		case arg == "--encrypt":
+			encrypt = true
+		case arg == "--offline":
+			offline = true
+		case arg == "--tz" && i+1 < len(os.Args):
+			i++
+			tz = os.Args[i]
+		case strings.HasPrefix(arg, "--tz="):
+			tz = strings.TrimPrefix(arg, "--tz=")
+		case arg == "--query" && i+1 < len(os.Args):
+			i++
+			query = os.Args[i]
+		case strings.HasPrefix(arg, "--query="):
+			query = strings.TrimPrefix(arg, "--query=")
+		case arg == "--profile" && i+1 < len(os.Args):
+			i++
+			profile = os.Args[i]
+		case strings.HasPrefix(arg, "--profile="):
+			profile = strings.TrimPrefix(arg, "--profile=")
+		default:
+			args = append(args, arg)
+		}
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	root := tte.NewDB(log)
+	db, err := tte.ProfileDB(root, profile)
+	if err != nil {
+		log.Fatal("failed to open profile", "profile", profile, "error", err)
+		os.Exit(1)
+	}
+	loc := time.Local
+	if len(tz) > 0 {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			log.Fatal("unknown time zone", "tz", tz, "error", err)
+			os.Exit(1)
+		}
+	}
+	if _, err = tte.CompileQuery(query, tte.QueryEnv{}); err != nil {
+		log.Fatal("bad --query", "error", err)
+		os.Exit(1)
+	}
+	a := &app{ctx: ctx, log: log, root: root, db: db, profile: profile, encrypt: encrypt, offline: offline, location: loc, query: query}
+
+	if len(args) > 0 {
+		if err := a.runCommand(args); err != nil {
+			log.Error(args[0], "error", err)
+			a.printRecovery(err)
+			cancel()
+			os.Exit(1)
+		}
+		return
+	}
 
 	if term.IsTerminal(0) {
 		log.Debug("in a term")
@@ -32,34 +101,45 @@ func main() {
 	}
 	log.Debug("terminal dimaensions", "width", width, "height", height)
 
-	if len(os.Args) > 1 {
-		for _, arg := range os.Args {
-			switch arg {
-			case "-v", "--verbose":
-				log.Level = logging.LogLevelDebug
-			}
-		}
-	}
-	err = a.extablishSession()
-	if err != nil {
+	if a.offline {
+		a.goOffline("--offline")
+	} else if err = a.extablishSession(); tte.Unreachable(err) {
+		a.goOffline("tabletop.events is unreachable")
+	} else if err != nil {
 		log.Fatal("failed to establish tabletop.events session", "error", err)
+		a.printRecovery(err)
 		return
 	}
 
 	con := a.SelectConvention()
-	println(tui.H3.Border(tui.DataBorder, true).Render(
-		fmt.Sprintf("%s : %s - %s\n\t%s\n\thttp://tabletop.events%s",
-			con.Name, con.StartDate, con.EndDate, con.WebsiteURI, con.ViewURI)))
+	header := fmt.Sprintf("%s : %s - %s\n\t%s\n\thttp://tabletop.events%s",
+		con.Name, con.StartDate, con.EndDate, con.WebsiteURI, con.ViewURI)
+	if a.offline {
+		header += "\n\t(offline: cached data only)"
+	}
+	println(tui.H3.Border(tui.DataBorder, true).Render(header))
 	a.println("selected:", con.Name)
 	// os.Exit(1)
 
 	var evz []tte.ConventionEvent
 	evz, err = a.getEvents()
+	if errors.Is(err, context.Canceled) {
+		log.Info("cancelled")
+		return
+	}
 	if err != nil {
 		log.Fatal("failed to get events", "con", con.ViewURI, "error", err)
+		a.printRecovery(err)
 	}
 	log.Info("found", "event_count", len(evz))
 
+	if a.times, err = a.s.TimetableContext(a.ctx, con, evz); err != nil {
+		log.Error("failed to get dayparts, event times may be missing", "error", err)
+	}
+
+	a.readLikesFromCache()
+	a.showLikedChanges()
+
 	counts, eventTypeURIByTypeName := a.getEventTypes(evz)
 
 	log.Info("found", "event_type_count", len(counts))
@@ -71,8 +151,7 @@ func main() {
 		eventTypeNameByURI[v] = k
 	}
 
-	a.readLikesFromCache()
-	var allLiked = a.likes
+	var allLiked = a.likes.URIs()
 
 	var stop bool
 	for !stop {
@@ -80,14 +159,11 @@ func main() {
 		filteredEvents := a.filterEventTypes(evz, eventTypeURIByTypeName)
 		log.Info("filtered events", "filtered", len(filteredEvents), "total", len(evz))
 
-		sort.Slice(filteredEvents, func(i, j int) bool {
-			if filteredEvents[i].StartdaypartName != filteredEvents[j].StartdaypartName {
-				return filteredEvents[i].StartdaypartName.Compare(filteredEvents[j].StartdaypartName)
-			}
-			return filteredEvents[i].Name < filteredEvents[j].Name
-		})
+		a.times.Sort(filteredEvents)
 
-		a.displayEvents(log, width, filteredEvents, eventTypeNameByURI)
+		conflicts := a.likedConflicts(evz)
+		a.displayEvents(log, width, filteredEvents, eventTypeNameByURI, conflicts)
+		a.printConflicts(conflicts)
 
 		var like = false
 
@@ -110,7 +186,7 @@ func main() {
 					}
 					value := fe.ViewURI
 					eventType := eventTypeNameByURI[fe.Relationships.Type]
-					key := fmt.Sprintf("%4d - [%-16s] %s [%s] (%s)", fe.EventNumber, eventType, tui.H4.Render(fe.Name), string(fe.StartdaypartName), fmt.Sprintf("%s", time.Duration(fe.Duration)*time.Minute))
+					key := fmt.Sprintf("%4d - [%-16s] %s [%s] (%s)", fe.EventNumber, eventType, tui.H4.Render(fe.Name), a.startOf(fe), fmt.Sprintf("%s", time.Duration(fe.Duration)*time.Minute))
 					options = append(options, huh.NewOption(key, value))
 				}
 				if len(options) > 0 {
@@ -144,7 +220,7 @@ func main() {
 					}
 					value := fe.ViewURI
 					eventType := eventTypeNameByURI[fe.Relationships.Type]
-					key := fmt.Sprintf("%4d - [%-16s] %s [%s] (%s)", fe.EventNumber, eventType, tui.H4.Render(fe.Name), string(fe.StartdaypartName), fmt.Sprintf("%s", time.Duration(fe.Duration)*time.Minute))
+					key := fmt.Sprintf("%4d - [%-16s] %s [%s] (%s)", fe.EventNumber, eventType, tui.H4.Render(fe.Name), a.startOf(fe), fmt.Sprintf("%s", time.Duration(fe.Duration)*time.Minute))
 					options = append(options, huh.NewOption(key, value))
 				}
 				if len(options) > 0 {
@@ -171,9 +247,7 @@ func main() {
 				}
 			}
 		}
-		sort.Strings(allLiked)
-		a.likes = allLiked
-		a.db.Store("liked", con.ViewURI, "txt", []byte(strings.Join(allLiked, "\n")))
+		a.syncLikes(allLiked, evz)
 		huh.NewConfirm().
 			Title("again?").
 			Affirmative("No.").
@@ -191,24 +265,10 @@ func main() {
 		WithTheme(huh.ThemeBase16()).
 		Run()
 
-	pred := func(ce tte.ConventionEvent) bool {
-		for _, l := range allLiked {
-			if l == ce.ViewURI {
-				return true
-			}
-		}
-		return false
-	}
-
-	filtered := tte.FilterableConventionEvents(evz).Filter(pred)
-	sort.Slice(filtered, func(i, j int) bool {
-		if filtered[i].StartdaypartName != filtered[j].StartdaypartName {
-			return filtered[i].StartdaypartName.Compare(filtered[j].StartdaypartName)
-		}
-		return filtered[j].Name < filtered[j].Name
-	})
+	filtered := tte.FilterableConventionEvents(evz).Filter(a.isLiked)
+	a.times.Sort(filtered)
 
-	sort.Strings(allLiked)
+	conflicts := a.likedConflicts(evz)
 	for _, like := range filtered {
 		if open {
 			var url string
@@ -218,41 +278,126 @@ func main() {
 			cmd := exec.Command("open", url)
 			_, _ = cmd.Output()
 		} else {
-			log.Info("liked", "uri", like.ViewURI, "name", like.Name)
+			log.Info("liked", "uri", like.ViewURI, "name", like.Name, "conflicts", conflicts.Has(like))
 		}
 	}
-	a.db.Store("liked", con.ViewURI, "txt", []byte(strings.Join(allLiked, "\n")))
+	a.printConflicts(conflicts)
+
+	var plan bool
+	huh.NewConfirm().
+		Title("build an itinerary from liked events?").
+		Affirmative("Yes!").
+		Negative("No.").
+		Value(&plan).
+		WithTheme(huh.ThemeBase16()).
+		Run()
+	if plan {
+		a.printItinerary(tte.BuildItinerary(evz, a.likes, a.times, defaultItineraryConstraints))
+	}
 }
 
+// readLikesFromCache loads the liked events of a.con. Likes that could not
+// be read at all are left untouched: syncLikes will not overwrite them.
 func (a *app) readLikesFromCache() {
-	var b []byte
-	b, _ = a.db.Read("liked", a.con.ViewURI, "txt")
-	a.likes = strings.Split(string(b), "\n")
+	var err error
+	a.likes, err = tte.ReadLikes(a.db, a.con)
+	a.likesUnreadable = false
+	switch {
+	case errors.Is(err, tte.ErrIncompatibleRecord):
+		a.log.Error("liked events were unreadable and have been set aside, starting over", "error", err)
+	case err == nil, errors.Is(err, fs.ErrNotExist):
+	default:
+		a.likesUnreadable = true
+		a.log.Error("failed to read liked events, likes will not be saved this run", "error", err)
+	}
 }
 
 type app struct {
-	con   tte.Convention
+	ctx context.Context
+	con tte.Convention
+	// root holds the caches shared by every profile, db the profile's own data
+	root  tte.DB
 	db    tte.DB
-	likes []string
-	log   logging.Logger
-	s     tte.Session
+	likes tte.Likes
+	// likesUnreadable is set when the stored likes could not be read and
+	// must not be overwritten
+	likesUnreadable bool
+	log             logging.Logger
+	s               tte.Session
+
+	username string
+	password string
+	encrypt  bool
+	// offline browses cached data without logging in
+	offline bool
+	// location is the time zone event times are shown in
+	location *time.Location
+	// times holds when each event of con runs
+	times tte.Timetable
+	// profile names the account whose credentials and likes are in db
+	profile string
+	// query filters browsed events, see tte.CompileQuery
+	query string
 }
 
-func (a *app) isLiked(ce tte.ConventionEvent) bool {
-	for _, l := range a.likes {
-		if l == ce.ViewURI {
-			return true
+// profileEnv chooses the profile when --profile is not given.
+const profileEnv = "TTE_PROFILE"
+
+// passphraseEnv holds the passphrase that encrypts cached credentials.
+const passphraseEnv = "TTE_PASSPHRASE"
+
+// clientOptions configures clients to renew sessions by asking for the
+// login again and to keep secrets encrypted when a passphrase is given in
+// TTE_PASSPHRASE, when --encrypt is set or when they were encrypted before.
+func (a *app) clientOptions() (opts []tte.ClientOption, err error) {
+	opts = append(opts,
+		tte.WithCredentialProvider(tte.CredentialFunc(a.credentials)),
+		tte.WithProfile(a.profile),
+		tte.WithTimeZone(a.location),
+	)
+
+	passphrase := os.Getenv(passphraseEnv)
+	if len(passphrase) == 0 && (a.encrypt || tte.HasEncryptedSecrets(a.db)) {
+		err = huh.NewInput().
+			Title("unlock tabletop.events credentials").
+			Prompt("passphrase:").
+			EchoMode(huh.EchoModePassword).
+			Value(&passphrase).
+			WithTheme(huh.ThemeBase16()).
+			Run()
+		if err != nil {
+			return opts, err
 		}
 	}
-	return false
+	if len(passphrase) == 0 {
+		return opts, nil
+	}
+	var store tte.EncryptedSecretStore
+	store, err = tte.NewEncryptedSecretStore(a.db, []byte(passphrase))
+	if err != nil {
+		return opts, err
+	}
+	return append(opts, tte.WithSecretStore(store)), nil
+}
+
+// startOf shows when ev runs, or its daypart name when that is unknown.
+func (a *app) startOf(ev tte.ConventionEvent) string {
+	if t, ok := a.times.Get(ev); ok {
+		return t.Format()
+	}
+	return string(ev.StartdaypartName)
+}
+
+func (a *app) isLiked(ce tte.ConventionEvent) bool {
+	return a.likes.Has(ce.ViewURI)
 }
-func (a *app) displayEvents(log logging.Logger, width int, events []tte.ConventionEvent, eventTypeNameByURI map[string]string) {
-	keys := []string{"name", "number", "type", "start", "duration", "description", "publisher", "host group", "game master", "url"} //, "host"}
+func (a *app) displayEvents(log logging.Logger, width int, events []tte.ConventionEvent, eventTypeNameByURI map[string]string, conflicts tte.Conflicts) {
+	keys := []string{"name", "number", "type", "start", "duration", "seats", "conflicts", "description", "publisher", "host group", "game master", "url"} //, "host"}
 	const padding = 8
 	var maxFieldWidth = 80 // width - 12 - padding - 18
 	likeMap := make(map[string]struct{})
 	for _, l := range a.likes {
-		likeMap[l] = struct{}{}
+		likeMap[l.ViewURI] = struct{}{}
 	}
 	for _, ev := range events {
 		var out string
@@ -263,12 +408,18 @@ func (a *app) displayEvents(log logging.Logger, width int, events []tte.Conventi
 			liked := logging.WarnStyle.Style.Bold(true).Render("(*)")
 			name = fmt.Sprintf("%s%s", liked, ev.Name)
 		}
+		if conflicts.Has(ev) {
+			conflict := logging.ErrorStyle.Style.Bold(true).Render("(!)")
+			name = fmt.Sprintf("%s%s", conflict, name)
+		}
 		m := map[string]string{
 			"name":        name,
 			"number":      fmt.Sprintf("%d", ev.EventNumber),
 			"type":        eventTypeNameByURI[ev.Relationships.Type],
-			"start":       string(ev.StartdaypartName),
+			"start":       a.startOf(ev),
 			"duration":    fmt.Sprintf("%s", time.Duration(ev.Duration)*time.Minute),
+			"seats":       ev.Seats(),
+			"conflicts":   a.conflictNames(conflicts, ev),
 			"description": strip(ev.Description, "\n"),
 			"publisher":   ev.CustomFields.Publisher,
 			"host group":  ev.CustomFields.HostingGroup,
@@ -279,6 +430,9 @@ func (a *app) displayEvents(log logging.Logger, width int, events []tte.Conventi
 		}
 		for _, key := range keys {
 			value := m[key]
+			if (key == "conflicts" || key == "seats") && len(value) == 0 {
+				continue
+			}
 			if len(value) < maxFieldWidth {
 				out += fmt.Sprintf("%12s: %s\n", key, value)
 			} else {
@@ -384,8 +538,16 @@ func (a *app) filterEventTypes(events []tte.ConventionEvent, eventTypeURIByTypeN
 		areLiked    string
 	)
 
+	env := tte.QueryEnv{TypeNames: eventTypeNameByURI, Times: a.times, Likes: a.likes}
 	huh.NewForm(
 		huh.NewGroup(
+			huh.NewInput().Title("Query").
+				Description(`e.g. type:RPG day:friday gm:"smith" duration<=240 seats>0 -cancelled (publisher:wizkids OR complexity:light)`).
+				Value(&a.query).
+				Validate(func(q string) error {
+					_, err := tte.CompileQuery(q, env)
+					return err
+				}),
 			huh.NewMultiSelect[string]().Title("Event Type(s)").
 				Options(eventTypeOpts...).Value(&eventTypes),
 			huh.NewInput().Title("ID").Value(&id),
@@ -402,6 +564,11 @@ func (a *app) filterEventTypes(events []tte.ConventionEvent, eventTypeURIByTypeN
 		Run()
 
 	var pred []tte.EventPredicate
+	if p, err := tte.CompileQuery(a.query, env); err != nil {
+		a.log.Error("ignoring query", "query", a.query, "error", err)
+	} else {
+		pred = append(pred, p)
+	}
 	if len(title) > 0 {
 		pred = append(pred, func(ce tte.ConventionEvent) bool {
 			return strings.Contains(strings.ToLower(ce.Name), strings.ToLower(title))
@@ -414,20 +581,7 @@ func (a *app) filterEventTypes(events []tte.ConventionEvent, eventTypeURIByTypeN
 	}
 	if len(areLiked) > 0 && areLiked != "either" {
 		pred = append(pred, func(ce tte.ConventionEvent) bool {
-			if areLiked == "liked" {
-				for _, l := range a.likes {
-					if l == ce.ViewURI {
-						return true
-					}
-				}
-				return false
-			}
-			for _, l := range a.likes {
-				if l == ce.ViewURI {
-					return false
-				}
-			}
-			return true
+			return a.isLiked(ce) == (areLiked == "liked")
 		})
 	}
 	if len(host) > 0 {
@@ -485,8 +639,11 @@ func (a *app) getEventTypes(evz []tte.ConventionEvent) (map[string]int, map[stri
 		s   tte.Session = a.s
 	)
 	for _, ev := range evz {
+		if a.ctx.Err() != nil {
+			break
+		}
 		if cet, ok = eventTypeByURI[ev.Relationships.Type]; !ok {
-			cet, err = s.GetConventionEventType(ev.Relationships.Type)
+			cet, err = s.GetConventionEventTypeContext(a.ctx, ev.Relationships.Type)
 			if err != nil {
 				log.Error("failed to get event type from event", "event_type_uri", ev.Relationships.Type, "event_number", ev.EventNumber, "error", err)
 			}
@@ -516,29 +673,60 @@ func (a *app) getEvents() (events []tte.ConventionEvent, err error) {
 	cache, err := s.GetCachedConventionEvents(con)
 	events = cache.ConventionEvents
 	if err == nil {
-		ignoreCachedEventInfo = false
-		huh.NewConfirm().
-			Title(fmt.Sprintf("cached convention event data was found [%s old], shall we use it?", cache.Age)).
-			Affirmative("No.").
-			Negative("Yes!").
-			Value(&ignoreCachedEventInfo).
-			WithTheme(huh.ThemeBase16()).
-			Run()
+		ignoreCachedEventInfo = a.refreshStale("convention event data", cache.Age, cache.TTL, cache.Fresh)
 	} else {
 		log.Error("GetCachedConventionEvents", "error", err)
 	}
 	log.Info("use cache?", "ignoreCachedEventInfo", ignoreCachedEventInfo)
 	if ignoreCachedEventInfo {
-		events, err = s.GetConventionEvents(con)
-
+		var r tte.EventRefresh
+		r, err = s.RefreshConventionEventsContext(a.ctx, con)
+		events = r.Events
+		log.Info("refreshed events", "full", r.Full, "updated", r.Updated)
 	}
 	return events, err
 }
 
+// refreshStale uses fresh cached data without asking and offers to refresh
+// data its cache policy considers stale.
+func (a *app) refreshStale(what string, age time.Duration, ttl time.Duration, fresh bool) (refresh bool) {
+	if fresh {
+		a.log.Info("using cached "+what, "age", age, "fresh_for", ttl)
+		return false
+	}
+	if a.offline {
+		a.log.Info("offline, using stale cached "+what, "age", age, "fresh_for", ttl)
+		return false
+	}
+	refresh = true
+	huh.NewConfirm().
+		Title(fmt.Sprintf("cached %s is stale [%s old, fresh for %s], shall we refresh it?", what, age, ttl)).
+		Affirmative("Yes!").
+		Negative("No.").
+		Value(&refresh).
+		WithTheme(huh.ThemeBase16()).
+		Run()
+	return refresh
+}
+
+// goOffline switches to browsing cached data only, for the given reason.
+func (a *app) goOffline(reason string) {
+	a.offline = true
+	a.s = tte.NewOfflineClient(a.log, a.root, tte.WithProfile(a.profile), tte.WithTimeZone(a.location)).OfflineSession()
+	a.log.Warn(reason + ": browsing cached data only; refreshing and logging in are disabled")
+}
+
 func (a *app) extablishSession() error {
 	var log logging.Logger = a.log
 	var useCachedApiKey bool = true
-	c, err := tte.RestoreClient(log)
+	opts, err := a.clientOptions()
+	if err != nil {
+		return err
+	}
+	c, err := tte.RestoreClient(log, a.root, opts...)
+	if errors.Is(err, tte.ErrBadPassphrase) {
+		return err
+	}
 	useCachedApiKey = err == nil
 	if err != nil || !useCachedApiKey {
 		var apiKey string
@@ -548,50 +736,69 @@ func (a *app) extablishSession() error {
 			Value(&apiKey).
 			WithTheme(huh.ThemeBase16()).
 			Run()
-		c = tte.NewClient(log, apiKey)
+		c = tte.NewClient(log, a.root, apiKey, opts...)
 	}
 
 	var s tte.Session
-	s, err = c.RestoreSession()
+	s, err = c.RestoreSessionContext(a.ctx)
+	if tte.Unreachable(err) {
+		return err
+	}
 	if err != nil { //|| !useCachedSessionId {
 		log.Info("creating a new session")
 
-		var username string
-		var password string
-		form := huh.NewForm(
-			huh.NewGroup(
-				huh.NewNote().
-					Title("tabletop.evetns login"),
-			),
-			huh.NewGroup(
-				huh.NewInput().
-					// Title("tabletop.evetns login").
-					Prompt("username:").
-					Value(&username),
-				huh.NewInput().EchoMode(huh.EchoModePassword).
-					Prompt("password:").
-					Value(&password),
-			),
-			// WithTheme(huh.ThemeBase16()).
-			// Title("tabletop.evetns login").Description("the login page").WithShowErrors(true),
-		).
-			WithLayout(huh.LayoutStack).
-			WithShowErrors(true).
-			WithTheme(huh.ThemeBase16())
-		// form.Update(form.Init())
-		form.NextGroup()
-		form.Run()
-
-		if len(username) == 0 || len(password) == 0 {
+		var username, password string
+		username, password, err = a.credentials(a.ctx)
+		if err != nil {
 			log.Fatal("username and password must be provided")
 			os.Exit(1)
 		}
-		s, err = c.NewSession(username, password)
+		s, err = c.NewSessionContext(a.ctx, username, password)
 	}
 	a.s = s
 	return err
 }
 
+// credentials prompts for a tabletop.events login once per run and hands
+// the same answer to any later session renewal.
+func (a *app) credentials(ctx context.Context) (username string, password string, err error) {
+	if len(a.username) > 0 && len(a.password) > 0 {
+		return a.username, a.password, nil
+	}
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewNote().
+				Title("tabletop.evetns login"),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				// Title("tabletop.evetns login").
+				Prompt("username:").
+				Value(&username),
+			huh.NewInput().EchoMode(huh.EchoModePassword).
+				Prompt("password:").
+				Value(&password),
+		),
+		// WithTheme(huh.ThemeBase16()).
+		// Title("tabletop.evetns login").Description("the login page").WithShowErrors(true),
+	).
+		WithLayout(huh.LayoutStack).
+		WithShowErrors(true).
+		WithTheme(huh.ThemeBase16())
+	// form.Update(form.Init())
+	form.NextGroup()
+	err = form.RunWithContext(ctx)
+	if err != nil {
+		return username, password, err
+	}
+
+	if len(username) == 0 || len(password) == 0 {
+		return username, password, fmt.Errorf("username and password must be provided")
+	}
+	a.username, a.password = username, password
+	return username, password, nil
+}
+
 func (a *app) SelectConvention() tte.Convention {
 
 	var (
@@ -601,18 +808,15 @@ func (a *app) SelectConvention() tte.Convention {
 	ignoreCachedConventionInfo = true
 	cache, err := s.GetCachedActiveConventions()
 	if err == nil {
-		ignoreCachedConventionInfo = false
-		huh.NewConfirm().
-			Title(fmt.Sprintf("cached convention data was found [%s old], shall we use it?", cache.Age)).
-			Affirmative("No.").
-			Negative("Yes!").
-			Value(&ignoreCachedConventionInfo).
-			WithTheme(huh.ThemeBase16()).
-			Run()
+		ignoreCachedConventionInfo = a.refreshStale("convention data", cache.Age, cache.TTL, cache.Fresh)
 	}
 	cz := cache.Conventions
 	if ignoreCachedConventionInfo {
-		cz, err = s.GetActiveConventions()
+		cz, err = s.GetActiveConventionsContext(a.ctx)
+		if err != nil {
+			a.log.Error("failed to get conventions", "error", err)
+			a.printRecovery(err)
+		}
 	}
 	var conmap = make(map[string]tte.Convention)
 
@@ -639,3 +843,36 @@ func (a *app) SelectConvention() tte.Convention {
 	a.con = con
 	return con
 }
+
+// printRecovery tells the user what they can do about a gateway error.
+func (a *app) printRecovery(err error) {
+	var (
+		hint string
+		de   *tte.DecodeError
+	)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return
+	case errors.Is(err, tte.ErrBadPassphrase):
+		hint = "that passphrase does not unlock your cached credentials; check " + passphraseEnv + " or try again."
+	case errors.Is(err, tte.ErrSessionExpired):
+		hint = "your tabletop.events session expired; run buddy again to log in."
+	case errors.Is(err, tte.ErrAuthRequired):
+		hint = "tabletop.events rejected your credentials; check your api key, username and password."
+	case errors.Is(err, tte.ErrAdminRequired):
+		hint = "that needs convention admin rights on tabletop.events; ask a convention admin."
+	case errors.Is(err, tte.ErrNotFound):
+		hint = "tabletop.events could not find that; the convention or event may have been removed."
+	case errors.Is(err, tte.ErrRateLimited):
+		hint = "tabletop.events is rate limiting us; wait a minute and try again."
+	case errors.Is(err, tte.ErrOffline):
+		hint = "that needs tabletop.events, which buddy does not use offline; run it again once you are connected."
+	case errors.Is(err, tte.ErrUpstreamUnavailable):
+		hint = "tabletop.events is unreachable or having trouble; check your network or try again later."
+	case errors.As(err, &de):
+		hint = "tabletop.events sent an unexpected response; it may be down for maintenance."
+	default:
+		return
+	}
+	a.log.Info(hint)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dan-frohlich/tabetopevents/internal/gateway/tte"
+	"github.com/dan-frohlich/tabetopevents/internal/logging"
+)
+
+// likedConflicts finds the liked events of evz that overlap.
+func (a *app) likedConflicts(evz []tte.ConventionEvent) tte.Conflicts {
+	return a.times.Conflicts(tte.FilterableConventionEvents(evz).Filter(a.isLiked))
+}
+
+// printConflicts lists overlapping liked events by time slot.
+func (a *app) printConflicts(cz tte.Conflicts) {
+	if len(cz) == 0 {
+		return
+	}
+	out := logging.ErrorStyle.Style.Bold(true).Render(fmt.Sprintf("%d schedule conflict(s) among liked events", len(cz))) + "\n"
+	for _, c := range cz {
+		out += c.Slot.Format() + "\n"
+		for _, e := range c.Events {
+			t, _ := a.times.Get(e)
+			out += fmt.Sprintf("  %4d - %s [%s]\n", e.EventNumber, e.Name, t.Format())
+		}
+	}
+	println(lipgloss.NewStyle().Border(lipgloss.RoundedBorder(), true).Render(strings.TrimSuffix(out, "\n")))
+}
+
+// conflictNames lists the liked events ev overlaps.
+func (a *app) conflictNames(cz tte.Conflicts, ev tte.ConventionEvent) string {
+	var names []string
+	for _, e := range cz.With(a.times, ev) {
+		names = append(names, fmt.Sprintf("%d - %s", e.EventNumber, e.Name))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dan-frohlich/tabetopevents/internal/gateway/tte"
+)
+
+// watch defaults
+const (
+	defaultWatchInterval = 5 * time.Minute
+	defaultNearlyFull    = 2
+)
+
+func (a *app) watchCommand(args []string) (err error) {
+	w := tte.SeatWatch{Interval: defaultWatchInterval, NearlyFull: defaultNearlyFull}
+	var (
+		notifiers tte.Notifiers
+		bell      bool
+		rest      []string
+	)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var value string
+		if name, v, ok := strings.Cut(arg, "="); ok && strings.HasPrefix(arg, "--") {
+			arg, value = name, v
+		} else if (arg == "--every" || arg == "--nearly-full" || arg == "--hook") && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		switch arg {
+		case "--every":
+			w.Interval, err = time.ParseDuration(value)
+		case "--nearly-full":
+			w.NearlyFull, err = strconv.Atoi(value)
+		case "--bell":
+			bell = true
+		case "--desktop":
+			notifiers = append(notifiers, tte.NotifierFunc(desktopNotify))
+		case "--hook":
+			notifiers = append(notifiers, hookNotifier(value))
+		default:
+			if strings.HasPrefix(arg, "--") {
+				return fmt.Errorf("unknown watch option %q\n%s", arg, usage)
+			}
+			rest = append(rest, arg)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", arg, err)
+		}
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("watch needs a convention\n%s", usage)
+	}
+	if w.Interval < tte.MinWatchInterval {
+		return fmt.Errorf("--every must be at least %s", tte.MinWatchInterval)
+	}
+	if bell || len(notifiers) == 0 {
+		notifiers = append(notifiers, tte.NotifierFunc(bellNotify))
+	}
+	w.Notifier = notifiers
+	if a.offline {
+		return fmt.Errorf("watch: %w", tte.ErrOffline)
+	}
+	if a.con, err = a.findConvention(rest[0]); err != nil {
+		return err
+	}
+	if err = a.extablishSession(); err != nil {
+		return err
+	}
+
+	cache, err := a.s.GetCachedConventionEvents(a.con)
+	evz := cache.ConventionEvents
+	if err != nil {
+		var r tte.EventRefresh
+		if r, err = a.s.RefreshConventionEventsContext(a.ctx, a.con); err != nil {
+			return err
+		}
+		evz = r.Events
+	}
+	a.readLikesFromCache()
+	liked := tte.FilterableConventionEvents(evz).Filter(a.isLiked)
+	if len(liked) == 0 {
+		return fmt.Errorf("no liked events for %s, browse it to like some", a.con.Name)
+	}
+
+	// the cache may be days old, so alerts are raised against current seats
+	baseline, err := a.s.PollSeatsContext(a.ctx, liked)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	fmt.Printf("watching %d liked event(s) of %s every %s, ctrl-c to stop\n", len(baseline), a.con.Name, w.Interval)
+	for _, e := range baseline {
+		fmt.Printf("  %4d - %s (%s)\n", e.EventNumber, e.Name, e.Seats())
+	}
+	err = a.s.WatchSeatsContext(a.ctx, baseline, w)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// bellNotify rings the terminal bell and prints the alert.
+func bellNotify(_ context.Context, alert tte.SeatAlert) error {
+	_, err := fmt.Printf("\a%s %s\n", time.Now().Format(time.TimeOnly), alert)
+	return err
+}
+
+// desktopNotify shows the alert with the platform's notification command.
+func desktopNotify(ctx context.Context, alert tte.SeatAlert) error {
+	title := "buddy: " + string(alert.Kind)
+	body := fmt.Sprintf("#%d %s (%s)", alert.Event.EventNumber, alert.Event.Name, alert.Event.Seats())
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(body), strconv.Quote(title))
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	case "windows":
+		return fmt.Errorf("desktop notifications are not supported on %s, use --hook", runtime.GOOS)
+	default:
+		cmd = exec.CommandContext(ctx, "notify-send", title, body)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", cmd.Path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// hookNotifier runs a shell command for each alert, describing it in TTE_
+// environment variables.
+func hookNotifier(command string) tte.Notifier {
+	return tte.NotifierFunc(func(ctx context.Context, alert tte.SeatAlert) error {
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Env = append(os.Environ(),
+			"TTE_ALERT="+string(alert.Kind),
+			"TTE_ALERT_MESSAGE="+alert.String(),
+			"TTE_EVENT_NAME="+alert.Event.Name,
+			"TTE_EVENT_NUMBER="+strconv.Itoa(alert.Event.EventNumber),
+			"TTE_EVENT_URL=https://tabletop.events"+alert.Event.ViewURI,
+			"TTE_SEATS_BEFORE="+strconv.Itoa(alert.From),
+			"TTE_SEATS_LEFT="+strconv.Itoa(alert.To),
+			"TTE_WAIT_COUNT="+strconv.Itoa(alert.Event.WaitCount),
+		)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("hook %q: %w", command, err)
+		}
+		return nil
+	})
+}
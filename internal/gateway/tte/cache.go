@@ -0,0 +1,227 @@
+package tte
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// record ids the gateway caches data under; each is a cache kind with its
+// own CachePolicy
+const (
+	CacheConventions = "conventions"
+	CacheEvents      = "events"
+	CacheEventType   = "event_type"
+)
+
+// CachePolicy decides how long one kind of cached data stays fresh.
+type CachePolicy struct {
+	Kind        string
+	Description string
+	// TTL is how long the data stays fresh as of now. con is the convention
+	// the data belongs to, or the zero Convention when it has none.
+	TTL func(now time.Time, con Convention) time.Duration
+	// PerProfile is set for data each profile keeps in its own DB rather
+	// than in the shared one.
+	PerProfile bool
+}
+
+// CachePolicies holds a CachePolicy per cache kind.
+type CachePolicies map[string]CachePolicy
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// DefaultCachePolicies refresh events often while their convention runs,
+// when rooms and seats change by the hour, and rarely otherwise.
+var DefaultCachePolicies = CachePolicies{
+	CacheConventions: {
+		Kind:        CacheConventions,
+		Description: "7 days",
+		TTL:         func(time.Time, Convention) time.Duration { return week },
+	},
+	CacheEvents: {
+		Kind:        CacheEvents,
+		Description: "1 hour during the convention, 1 day before it, 7 days after it",
+		TTL: func(now time.Time, con Convention) time.Duration {
+			start, end, err := con.Dates()
+			switch {
+			case err != nil:
+				return day
+			case now.Before(start):
+				return day
+			case now.Before(end):
+				return time.Hour
+			default:
+				return week
+			}
+		},
+	},
+	CacheEventType: {
+		Kind:        CacheEventType,
+		Description: "7 days",
+		TTL:         func(time.Time, Convention) time.Duration { return week },
+	},
+	CacheDayparts: {
+		Kind:        CacheDayparts,
+		Description: "7 days",
+		TTL:         func(time.Time, Convention) time.Duration { return week },
+	},
+	// responses are revalidated on every request, so this only bounds how
+	// long they are kept for offline use
+	CacheHTTP: {
+		Kind:        CacheHTTP,
+		Description: "kept 7 days for revalidation and offline use",
+		TTL:         func(time.Time, Convention) time.Duration { return week },
+		PerProfile:  true,
+	},
+	// StoreSnapshot keeps the last maxSnapshots of each convention; this
+	// lets old ones of conventions no longer fetched go too
+	CacheSnapshot: {
+		Kind:        CacheSnapshot,
+		Description: "30 days, at most 20 per convention",
+		TTL:         func(time.Time, Convention) time.Duration { return 30 * day },
+	},
+}
+
+// TTL is how long data of kind stays fresh. Kinds without a policy never
+// expire.
+func (cp CachePolicies) TTL(kind string, now time.Time, con Convention) (ttl time.Duration, ok bool) {
+	p, ok := cp[kind]
+	if !ok || p.TTL == nil {
+		return 0, false
+	}
+	return p.TTL(now, con), true
+}
+
+// Fresh reports whether data of kind that is age old is still fresh.
+func (cp CachePolicies) Fresh(kind string, age time.Duration, con Convention) bool {
+	ttl, ok := cp.TTL(kind, time.Now(), con)
+	return !ok || age <= ttl
+}
+
+// conventionDateLayouts are the formats tabletop.events uses for dates.
+var conventionDateLayouts = []string{"2006-01-02 15:04:05", "2006-01-02"}
+
+func parseTTEDate(s string, loc *time.Location) (t time.Time, err error) {
+	for _, layout := range conventionDateLayouts {
+		if t, err = time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return t, fmt.Errorf("unrecognized date %q", s)
+}
+
+// Dates returns when the convention starts and ends. A date without a time
+// of day ends at the end of that day.
+func (con Convention) Dates() (start time.Time, end time.Time, err error) {
+	if start, err = parseTTEDate(con.StartDate, time.Local); err != nil {
+		return start, end, err
+	}
+	if end, err = parseTTEDate(con.EndDate, time.Local); err != nil {
+		return start, end, err
+	}
+	if !strings.Contains(con.EndDate, ":") {
+		end = end.Add(day)
+	}
+	return start, end, nil
+}
+
+// CacheEntry describes one cached record governed by a CachePolicy.
+type CacheEntry struct {
+	Key  Key
+	Size int
+	Age  time.Duration
+	// TTL is how long the record stays fresh under its policy.
+	TTL time.Duration
+	// Convention names the convention the record belongs to, if any.
+	Convention string
+}
+
+func (ce CacheEntry) Expired() bool {
+	return ce.Age > ce.TTL
+}
+
+// CacheEntries lists every record in db that a policy governs, including
+// those profiles keep of PerProfile kinds. Event caches are matched to their
+// convention through the conventions cache to pick their TTL.
+func CacheEntries(db DB, policies CachePolicies) (entries []CacheEntry, err error) {
+	keys, err := db.List("")
+	if err != nil {
+		return entries, err
+	}
+
+	conventions := map[string]Convention{}
+	if cache, _, e := readCachedConventions(db); e == nil {
+		for _, con := range cache.Items {
+			conventions[cleanKind(con.ViewURI)] = con
+		}
+	}
+
+	now := time.Now()
+	for _, k := range keys {
+		p, ok := policies[k.ID]
+		if !ok || (strings.HasPrefix(k.Kind, profilesKind+"/") && !p.PerProfile) {
+			continue
+		}
+		con := conventions[k.Kind]
+		if k.ID == CacheSnapshot {
+			con = conventions[path.Dir(path.Dir(k.Kind))]
+		}
+		ce := CacheEntry{Key: k, Convention: con.Name}
+		ce.TTL, _ = policies.TTL(k.ID, now, con)
+		if ce.Age, err = db.CacheAge(k.ID, k.Kind, k.DataType); err != nil {
+			return entries, err
+		}
+		var b []byte
+		if b, err = db.Read(k.ID, k.Kind, k.DataType); err != nil {
+			return entries, err
+		}
+		ce.Size = len(b)
+		entries = append(entries, ce)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Key.ID < entries[j].Key.ID
+	})
+	return entries, nil
+}
+
+// CacheStats summarizes the cached records of one kind.
+type CacheStats struct {
+	Kind    string
+	Count   int
+	Expired int
+	Size    int
+	Oldest  time.Duration
+	Newest  time.Duration
+}
+
+// Stats groups entries by kind.
+func Stats(entries []CacheEntry) (stats []CacheStats) {
+	byKind := map[string]*CacheStats{}
+	for _, e := range entries {
+		st, ok := byKind[e.Key.ID]
+		if !ok {
+			st = &CacheStats{Kind: e.Key.ID, Newest: e.Age}
+			byKind[e.Key.ID] = st
+		}
+		st.Count++
+		st.Size += e.Size
+		if e.Expired() {
+			st.Expired++
+		}
+		st.Oldest = max(st.Oldest, e.Age)
+		st.Newest = min(st.Newest, e.Age)
+	}
+	for _, st := range byKind {
+		stats = append(stats, *st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Kind < stats[j].Kind
+	})
+	return stats
+}
@@ -0,0 +1,67 @@
+package tte
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEventsTTLFollowsConventionPhase(t *testing.T) {
+	con := Convention{StartDate: "2025-07-31 08:00:00", EndDate: "2025-08-03"}
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	cases := map[string]time.Duration{
+		"2025-07-01 12:00": day,
+		"2025-08-01 12:00": time.Hour,
+		"2025-08-03 23:00": time.Hour,
+		"2025-08-04 01:00": week,
+	}
+	for now, want := range cases {
+		if ttl, _ := DefaultCachePolicies.TTL(CacheEvents, at(now), con); ttl != want {
+			t.Errorf("events ttl at %s = %s, want %s", now, ttl, want)
+		}
+	}
+}
+
+func TestCacheEntriesSkipProfilesAndUnknownKinds(t *testing.T) {
+	db := NewMemoryDB()
+	con := Convention{Name: "Test Con", ViewURI: "/convention/test-con", StartDate: "2025-07-31", EndDate: "2025-08-03"}
+	b, _ := json.Marshal(Conventions{Items: []Convention{con}})
+	_ = db.Store(CacheConventions, CacheConventions, "json", b)
+	_ = db.Store(CacheEvents, con.ViewURI, "json", []byte(`{"items":[]}`))
+	_ = db.Store("liked", con.ViewURI, "txt", []byte("/event/a"))
+	profile, _ := ProfileDB(db, "gm")
+	_ = profile.Store(CacheEvents, con.ViewURI, "json", []byte(`{}`))
+	// http responses are kept per profile and still governed
+	_ = profile.Store(CacheHTTP, "/api/user/1/abcdef", "json", []byte(`{}`))
+	if _, err := StoreSnapshot(db, con, "test", nil); err != nil {
+		t.Fatalf("StoreSnapshot: %v", err)
+	}
+
+	entries, err := CacheEntries(db, DefaultCachePolicies)
+	if err != nil {
+		t.Fatalf("CacheEntries: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("entries = %+v, want conventions, events, http and snapshot", entries)
+	}
+	if entries[3].Key.ID != CacheSnapshot || entries[3].Convention != "Test Con" || entries[3].TTL != 30*day {
+		t.Fatalf("snapshot entry = %+v", entries[3])
+	}
+	if entries[2].Key.ID != CacheHTTP || entries[2].Key.Kind != "profiles/gm/api/user/1/abcdef" {
+		t.Fatalf("http entry = %+v", entries[2])
+	}
+	if entries[1].Key.ID != CacheEvents || entries[1].Convention != "Test Con" || entries[1].Size != 12 {
+		t.Fatalf("events entry = %+v", entries[1])
+	}
+
+	stats := Stats(entries)
+	if len(stats) != 4 || stats[0].Kind != CacheConventions || stats[1].Count != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
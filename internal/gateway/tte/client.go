@@ -1,40 +1,139 @@
 package tte
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/dan-frohlich/tabetopevents/internal/logging"
 )
 
-const baseURL = `https://tabletop.events`
+const defaultBaseURL = `https://tabletop.events`
 
 type Client struct {
-	key string
-	db  DB
-	log logging.Logger
+	key         string
+	db          DB
+	log         logging.Logger
+	baseURL     string
+	http        *http.Client
+	userAgent   string
+	retry       RetryPolicy
+	limiter     *tokenBucket
+	pageWorkers int
+	credentials CredentialProvider
+	secrets     SecretStore
+	profile     string
+
+	cachePolicies CachePolicies
+	// fullRefresh is how long incremental event refreshes go without a full one
+	fullRefresh time.Duration
+	// httpCache keeps GET responses in the profile's DB
+	httpCache bool
+	// offline answers requests from the http cache only
+	offline bool
+	// location is the time zone conventions are assumed to run in
+	location *time.Location
 }
 
-func RestoreClient(log logging.Logger) (c Client, err error) {
-	db := NewDB(log)
+const (
+	defaultRequestsPerSecond = 5
+	defaultBurst             = 10
+)
+
+func RestoreClient(log logging.Logger, db DB, opts ...ClientOption) (c Client, err error) {
+	c = newClient(log, db, "", opts...)
 	var b []byte
-	b, err = db.Read("apikey", "client", "txt")
+	b, err = c.secrets.Get(secretAPIKey)
 	if err != nil {
 		return c, err
 	}
 	if len(b) == 0 {
 		return c, fmt.Errorf("api key fialed to load")
 	}
-	return NewClient(log, string(b)), nil
+	c.key = string(b)
+	return c, nil
+}
+
+func NewClient(log logging.Logger, db DB, apikey string, opts ...ClientOption) Client {
+	c := newClient(log, db, apikey, opts...)
+	if err := c.secrets.Put(secretAPIKey, []byte(apikey)); err != nil {
+		c.log.Error("failed to store api key", "error", err)
+	}
+	return c
+}
+
+// NewOfflineClient returns a client that never contacts tabletop.events.
+// Its cached readers work as usual and GETs are answered from the http
+// cache; anything else fails with ErrOffline.
+func NewOfflineClient(log logging.Logger, db DB, opts ...ClientOption) Client {
+	return newClient(log, db, "", append(opts, withOffline())...)
+}
+
+// OfflineSession returns a session without an id for browsing cached data,
+// e.g. from an offline client.
+func (c Client) OfflineSession() (s Session) {
+	s = Session{client: c, log: c.log}
+	s.auth = newSessionAuth(s)
+	return s
+}
+
+// Offline reports whether the client was made by NewOfflineClient.
+func (c Client) Offline() bool {
+	return c.offline
 }
 
-func NewClient(log logging.Logger, apikey string) Client {
-	c := Client{key: apikey, db: NewDB(log), log: log}
-	c.db.Store("apikey", "client", "txt", []byte(apikey))
+// newClient applies opts over the defaults. The api key and session belong
+// to the client's profile; caches in db are shared by every profile.
+func newClient(log logging.Logger, db DB, apikey string, opts ...ClientOption) Client {
+	c := Client{
+		key:     apikey,
+		db:      db,
+		log:     log,
+		baseURL: defaultBaseURL,
+		http:    &http.Client{},
+		retry:   DefaultRetryPolicy,
+		limiter: newTokenBucket(defaultRequestsPerSecond, defaultBurst),
+
+		pageWorkers: defaultPageWorkers,
+
+		cachePolicies: DefaultCachePolicies,
+		fullRefresh:   defaultFullRefreshInterval,
+		httpCache:     true,
+		location:      time.Local,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	profileDB, err := ProfileDB(c.db, c.profile)
+	if err != nil {
+		c.log.Error("failed to open profile, using the default", "profile", c.profile, "error", err)
+	}
+	if c.secrets == nil {
+		c.secrets = NewPlainSecretStore(profileDB)
+	}
+	if c.offline {
+		hc := c.httpClient()
+		hc.Transport = offlineTransport{}
+		c.http = hc
+	}
+	if c.httpCache {
+		hc := c.httpClient()
+		hc.Transport = NewCachingTransport(hc.Transport, profileDB, c.log)
+		c.http = hc
+	}
+	migrated, err := MigrateSecrets(profileDB, c.secrets)
+	if err != nil {
+		c.log.Error("failed to migrate secrets", "error", err)
+	}
+	for _, name := range migrated {
+		c.log.Info("moved secret into the secret store", "name", name)
+	}
 	return c
 }
 
@@ -44,9 +143,31 @@ type SessionResponse struct {
 }
 
 func (c Client) RestoreSession() (s Session, err error) {
-	var log logging.Logger = c.log
+	return c.RestoreSessionContext(context.Background())
+}
+
+// RestoreSessionContext loads the cached session and verifies it is still
+// accepted by tabletop.events.
+func (c Client) RestoreSessionContext(ctx context.Context) (s Session, err error) {
+	s, err = c.CachedSession()
+	if err != nil {
+		return s, err
+	}
+
+	err = s.TestConnectionContext(ctx)
+
+	if err != nil {
+		return Session{}, err
+	}
+
+	return s, nil
+}
+
+// CachedSession loads the cached session without checking it is still
+// valid.
+func (c Client) CachedSession() (s Session, err error) {
 	var out []byte
-	out, err = c.db.Read("session", "session", "json")
+	out, err = c.secrets.Get(secretSession)
 	if err != nil {
 		return s, err
 	}
@@ -58,123 +179,271 @@ func (c Client) RestoreSession() (s Session, err error) {
 	if sr.Err != nil {
 		return sr.Session, sr.Err
 	}
+	if b, e := c.secrets.Get(secretUserName); e == nil {
+		sr.Session.userName = string(b)
+	}
 	sr.Session.client = c
-	sr.Session.log = log
+	sr.Session.log = c.log
+	sr.Session.auth = newSessionAuth(sr.Session)
+	return sr.Session, nil
+}
 
-	err = sr.Session.TestConnection()
+// SessionStatus describes the cached session.
+type SessionStatus struct {
+	ID       string
+	UID      string
+	UserName string
+	Age      time.Duration
+	Valid    bool
+	// Err is why the session is not valid.
+	Err error
+}
 
+// SessionStatusContext reports on the cached session, checking with
+// tabletop.events whether it is still valid.
+func (c Client) SessionStatusContext(ctx context.Context) (st SessionStatus, err error) {
+	var s Session
+	s, err = c.CachedSession()
 	if err != nil {
-		return s, err
+		return st, err
 	}
+	st.ID = s.ID()
+	st.UID = s.UID()
+	st.UserName = s.userName
+	st.Age, err = c.secrets.Age(secretSession)
+	if err != nil {
+		return st, err
+	}
+	st.Err = s.TestConnectionContext(ctx)
+	st.Valid = st.Err == nil
+	return st, nil
+}
 
-	return sr.Session, nil
+// ForgetAPIKey removes the cached api key so the next run asks for one.
+func (c Client) ForgetAPIKey() error {
+	return c.secrets.Delete(secretAPIKey)
 }
 
 func (c Client) NewSession(userName string, password string) (s Session, err error) {
+	return c.NewSessionContext(context.Background(), userName, password)
+}
+
+// NewSessionContext logs in to tabletop.events and caches the new session.
+func (c Client) NewSessionContext(ctx context.Context, userName string, password string) (s Session, err error) {
 	var log logging.Logger = c.log
 	params := map[string]string{
 		"username": userName,
 		"password": password,
 	}
 	log.Debug("starting session for", "username", userName)
-	out, err := c.httpPost(`/api/session`, params, nil, nil)
+	out, err := c.httpPost(ctx, `/api/session`, params, nil, nil)
 	if err != nil {
 		return s, err
 	}
 
-	err = c.db.Store("session", "session", "json", out)
+	err = c.secrets.Put(secretSession, out)
 	if err != nil {
 		c.log.Debug("failed to store session", "error", err)
 	}
 
 	sr := SessionResponse{}
-	err = json.Unmarshal(out, &sr)
+	err = decodeResponse(out, &sr)
 	if err != nil {
 		return s, err
 	}
 	if sr.Err != nil {
 		return sr.Session, sr.Err
 	}
-	log.Info("session created for", "username", userName, "session_id", sr.Session.ID)
+	log.Info("session created for", "username", userName, "session_id", sr.Session.ID())
+	err = c.secrets.Put(secretUserName, []byte(userName))
+	if err != nil {
+		c.log.Debug("failed to store session username", "error", err)
+	}
 	sr.Session.userName = userName
 	sr.Session.client = c
 	sr.Session.log = log
+	sr.Session.auth = newSessionAuth(sr.Session)
 
 	return sr.Session, err
 }
 
-func (c Client) httpGet(uri string, params map[string]string, headers map[string]string) (body []byte, err error) {
-	baseURL, err := url.Parse(baseURL)
+// sourceURL is the address uri is fetched from, recorded with cached data.
+func (c Client) sourceURL(uri string) string {
+	return strings.TrimSuffix(c.baseURL, "/") + uri
+}
+
+func (c Client) httpGet(ctx context.Context, uri string, params map[string]string, headers map[string]string) (body []byte, err error) {
+	var req *http.Request
+	req, err = c.newRequest(ctx, http.MethodGet, uri, c.values(params), nil)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-
-	q := baseURL.Query()
-	for k, v := range params {
-		q.Add(k, v)
+	for k, v := range headers {
+		req.Header.Set(k, v)
 	}
-	q.Add("api_key_id", c.key)
 
-	// Construct a new URL by copying the base URL and overriding the host
-	newURL := &url.URL{
-		Scheme:   baseURL.Scheme,
-		Host:     baseURL.Host,
-		Path:     uri,
-		RawQuery: q.Encode(),
-	}
+	c.log.Debug("getting:", "url", sanitize(req.URL.String()))
 
-	c.log.Debug("getting:", "url", sanitize(newURL.String()))
+	return c.send(req)
+}
 
-	resp, err := http.Get(newURL.String())
-	if err != nil {
-		return nil, err
+// httpPost posts to uri. Without a reqBody, params and the api key are sent
+// as a url encoded form so credentials never appear in the URL. A reqBody is
+// sent as is (json unless headers say otherwise) and params then go in the
+// query string, so they must not carry credentials.
+func (c Client) httpPost(ctx context.Context, uri string, params map[string]string, headers map[string]any, reqBody []byte) (respBody []byte, err error) {
+	var (
+		req         *http.Request
+		query       url.Values
+		body        []byte
+		contentType string
+	)
+	if reqBody == nil {
+		body = []byte(c.values(params).Encode())
+		contentType = "application/x-www-form-urlencoded"
+	} else {
+		query = c.values(params)
+		body = reqBody
+		contentType = "application/json"
 	}
 
-	body, err = io.ReadAll(resp.Body)
+	req, err = c.newRequest(ctx, http.MethodPost, uri, query, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
-	_ = resp.Body.Close()
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, fmt.Sprint(v))
+	}
+
+	c.log.Debug("posting", "url", sanitize(req.URL.String()), "content_type", req.Header.Get("Content-Type"), "bytes", len(body))
 
-	return body, err
+	return c.send(req)
 }
 
-func (c Client) httpPost(uri string, params map[string]string, headers map[string]any, reqBody []byte) (respBody []byte, err error) {
-	baseURL, err := url.Parse(baseURL)
+func (c Client) httpDelete(ctx context.Context, uri string, params map[string]string) (respBody []byte, err error) {
+	var req *http.Request
+	req, err = c.newRequest(ctx, http.MethodDelete, uri, c.values(params), nil)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	q := baseURL.Query()
+	c.log.Debug("deleting", "url", sanitize(req.URL.String()))
+
+	return c.send(req)
+}
+
+// values encodes params along with the api key.
+func (c Client) values(params map[string]string) url.Values {
+	q := url.Values{}
 	for k, v := range params {
 		q.Add(k, v)
 	}
 	q.Add("api_key_id", c.key)
+	return q
+}
 
-	// Construct a new URL by copying the base URL and overriding the host
-	newURL := &url.URL{
-		Scheme:   baseURL.Scheme,
-		Host:     baseURL.Host,
-		Path:     uri,
-		RawQuery: q.Encode(),
+// newRequest builds a request for uri relative to the client's base URL.
+func (c Client) newRequest(ctx context.Context, method string, uri string, query url.Values, body io.Reader) (*http.Request, error) {
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url %q: %w", c.baseURL, err)
 	}
 
-	c.log.Debug("posting", "url", sanitize(newURL.String()))
+	// Construct a new URL by copying the base URL and appending the uri to its path
+	newURL := &url.URL{
+		Scheme:   base.Scheme,
+		Host:     base.Host,
+		Path:     strings.TrimSuffix(base.Path, "/") + uri,
+		RawQuery: query.Encode(),
+	}
 
-	resp, err := http.Post(newURL.String(), "application/json", nil)
+	req, err := http.NewRequestWithContext(ctx, method, newURL.String(), body)
 	if err != nil {
 		return nil, err
 	}
+	if len(c.userAgent) > 0 {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return req, nil
+}
 
-	respBody, err = io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+// send runs req through the shared request pipeline: every attempt waits on
+// the client-wide rate limiter, and network failures, 429s and 5xxs are
+// retried with backoff. A non-2xx response carrying a tabletop.events error
+// envelope is handed back as a body for the caller to decode.
+func (c Client) send(req *http.Request) (body []byte, err error) {
+	hc := c.http
+	if hc == nil {
+		hc = http.DefaultClient
 	}
-	_ = resp.Body.Close()
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if err = c.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		if attempt > 0 && req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+
+		var resp *http.Response
+		resp, err = hc.Do(req)
+		if err == nil {
+			body, err = io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+		}
 
-	return respBody, err
+		var transient bool
+		switch {
+		case err != nil:
+			transient = retryableError(err)
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return body, nil
+		default:
+			transient = retryableStatus(resp.StatusCode)
+			if !transient && hasApiError(body) {
+				return body, nil
+			}
+			err = &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
+		}
+		// a request that may have been acted on, e.g. a login POST, is only
+		// sent again when the server said it was not: 429
+		retry := transient && (idempotent(req.Method) || (resp != nil && resp.StatusCode == http.StatusTooManyRequests))
+
+		if !retry || attempt >= c.retry.MaxRetries {
+			if transient && resp == nil {
+				err = fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+			}
+			return body, err
+		}
+		delay := c.retry.delay(attempt, resp)
+		c.log.Debug("retrying request", "url", sanitize(req.URL.String()), "attempt", attempt+1, "delay", delay, "error", err)
+		if err = sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// decodeResponse unmarshals a tabletop.events response body, reporting
+// anything that is not json as a *DecodeError.
+func decodeResponse(body []byte, v any) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return newDecodeError(body, err)
+	}
+	return nil
 }
 
+func hasApiError(body []byte) bool {
+	var envelope struct {
+		Err *ApiError `json:"error"`
+	}
+	return json.Unmarshal(body, &envelope) == nil && envelope.Err != nil
+}
+
+// sanitize scrubs credentials from logged URLs as a last line of defense;
+// httpPost already keeps them in the request body.
 func sanitize(s string) string {
 	return sanitizeUsername(sanitizePasswords(s))
 }
@@ -216,3 +485,11 @@ func sanitizeUsername(s string) string {
 	}
 	return s
 }
+
+// Profile is the name of the profile the client's credentials belong to.
+func (c Client) Profile() string {
+	if len(c.profile) == 0 {
+		return DefaultProfile
+	}
+	return c.profile
+}
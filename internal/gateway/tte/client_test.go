@@ -0,0 +1,194 @@
+package tte
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/dan-frohlich/tabetopevents/internal/logging"
+)
+
+var quietLog = logging.Log{Level: logging.LogLevelFatal + 1}
+
+// newFakeTTE starts a stand-in for the tabletop.events api with one
+// convention holding eventCount events, served 100 per page.
+func newFakeTTE(t *testing.T, eventCount int) *httptest.Server {
+	t.Helper()
+	writeJSON := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	paging := func(page, total int) Paging {
+		pages := (total + 99) / 100
+		return Paging{NextPageNumber: int64(page + 1), PageNumber: page, TotalItems: int64(total), TotalPages: int64(pages)}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/session", func(w http.ResponseWriter, r *http.Request) {
+		if len(r.URL.RawQuery) > 0 {
+			t.Errorf("session request leaked parameters into the url: %s", r.URL.RawQuery)
+		}
+		if r.PostFormValue("api_key_id") != "test-key" || len(r.PostFormValue("password")) == 0 {
+			writeJSON(w, map[string]any{"error": ApiError{Code: 401, Message: "bad api key"}})
+			return
+		}
+		writeJSON(w, map[string]any{"result": Session{id: "sess-1", uid: "user-1"}})
+	})
+	mux.HandleFunc("DELETE /api/session/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "sess-1" {
+			writeJSON(w, map[string]any{"error": ApiError{Code: 404, Message: "no such session"}})
+			return
+		}
+		writeJSON(w, map[string]any{"result": map[string]any{}})
+	})
+	mux.HandleFunc("GET /api/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("session_id") != "sess-1" {
+			writeJSON(w, map[string]any{"error": ApiError{Code: 441, Message: "session expired"}})
+			return
+		}
+		writeJSON(w, map[string]any{"error": ApiError{Code: 450, Message: "You must be an admin to do that."}})
+	})
+	mux.HandleFunc("GET /api/convention", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"result": Conventions{
+			Items:  []Convention{{ID: "con-1", Name: "Test Con", ViewURI: "/convention/test-con"}},
+			Paging: &Paging{TotalItems: 1, TotalPages: 1, NextPageNumber: 2},
+		}})
+	})
+	mux.HandleFunc("GET /api/convention/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("_page_number"))
+		var items []ConventionEvent
+		for i := (page - 1) * 100; i < page*100 && i < eventCount; i++ {
+			items = append(items, ConventionEvent{
+				ID:            fmt.Sprintf("ev-%d", i),
+				EventNumber:   i,
+				Name:          fmt.Sprintf("Event %d", i),
+				ConventionID:  r.PathValue("id"),
+				Relationships: ConventionEventRelationships{Type: "/api/eventtype/rpg"},
+			})
+		}
+		writeJSON(w, map[string]any{"result": ConventionEvents{Items: items, Paging: paging(page, eventCount)}})
+	})
+	mux.HandleFunc("GET /api/convention/{id}/dayparts", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"result": Dayparts{Items: []Daypart{
+			{ID: "dp-fri-9", Name: "Friday at  9:00 AM", StartDate: "2025-08-01 09:00:00", ConventionID: r.PathValue("id")},
+		}}})
+	})
+	mux.HandleFunc("GET /api/eventtype/{id}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"result": ConventionEventType{ID: r.PathValue("id"), Name: "RPG"}})
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSessionAgainstFakeServer(t *testing.T) {
+	srv := newFakeTTE(t, 250)
+	db := NewMemoryDB()
+	c := NewClient(quietLog, db, "test-key", WithBaseURL(srv.URL), WithHTTPClient(srv.Client()), WithUserAgent("buddy-test"))
+
+	s, err := c.NewSession("gm", "secret")
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	if s.ID() != "sess-1" {
+		t.Fatalf("session id = %q, want sess-1", s.ID())
+	}
+	if err = s.TestConnection(); err != nil {
+		t.Fatalf("TestConnection: %v", err)
+	}
+
+	restored, err := RestoreClient(quietLog, db, WithBaseURL(srv.URL), WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatalf("RestoreClient: %v", err)
+	}
+	if _, err = restored.RestoreSession(); err != nil {
+		t.Fatalf("RestoreSession: %v", err)
+	}
+
+	cz, err := s.GetActiveConventions()
+	if err != nil {
+		t.Fatalf("GetActiveConventions: %v", err)
+	}
+	if len(cz) != 1 || cz[0].ID != "con-1" {
+		t.Fatalf("conventions = %+v", cz)
+	}
+
+	ez, err := s.GetConventionEvents(cz[0])
+	if err != nil {
+		t.Fatalf("GetConventionEvents: %v", err)
+	}
+	if len(ez) != 250 {
+		t.Fatalf("got %d events, want 250", len(ez))
+	}
+	for i, e := range ez {
+		if e.EventNumber != i {
+			t.Fatalf("event %d out of order: %d", i, e.EventNumber)
+		}
+	}
+
+	cet, err := s.GetConventionEventType(ez[0].Relationships.Type)
+	if err != nil {
+		t.Fatalf("GetConventionEventType: %v", err)
+	}
+	if cet.Name != "RPG" {
+		t.Fatalf("event type name = %q, want RPG", cet.Name)
+	}
+}
+
+func TestSessionRejectedByFakeServer(t *testing.T) {
+	srv := newFakeTTE(t, 0)
+	c := NewClient(quietLog, NewMemoryDB(), "wrong-key", WithBaseURL(srv.URL))
+
+	if _, err := c.NewSession("gm", "secret"); err == nil {
+		t.Fatal("expected an error for a bad api key")
+	}
+}
+
+func TestSessionHonorsCancellation(t *testing.T) {
+	srv := newFakeTTE(t, 250)
+	c := NewClient(quietLog, NewMemoryDB(), "test-key", WithBaseURL(srv.URL))
+	s, err := c.NewSession("gm", "secret")
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = s.GetConventionEventsContext(ctx, Convention{ID: "con-1"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestLogoutClearsCachedSession(t *testing.T) {
+	srv := newFakeTTE(t, 0)
+	db := NewMemoryDB()
+	c := NewClient(quietLog, db, "test-key", WithBaseURL(srv.URL))
+	s, err := c.NewSession("gm", "secret")
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+
+	st, err := c.SessionStatusContext(context.Background())
+	if err != nil {
+		t.Fatalf("SessionStatus: %v", err)
+	}
+	if !st.Valid || st.UserName != "gm" || st.ID != "sess-1" {
+		t.Fatalf("status = %+v, want a valid session for gm", st)
+	}
+
+	if err = s.Logout(true); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, err = c.CachedSession(); err == nil {
+		t.Fatal("session still cached after logout")
+	}
+	if _, err = RestoreClient(quietLog, db); err == nil {
+		t.Fatal("api key still cached after logout")
+	}
+}
@@ -1,95 +1,68 @@
 package tte
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"time"
 )
 
 func (s Session) GetCachedActiveConventions() (cache ConventionCache, err error) {
-	var b []byte
-	b, err = s.client.db.Read("conventions", "conventions", "json")
+	var c Conventions
+	var r Record
+	c, r, err = readCachedConventions(s.client.db)
 	if err != nil {
 		s.log.Error("read", "error", err)
 		return cache, err
 	}
-	s.log.Debug("read", "bytes", len(b))
-	c := &Conventions{}
-	err = json.Unmarshal(b, c)
-	if err != nil {
-		return cache, err
-	}
 	if len(c.Items) == 0 {
 		return cache, fmt.Errorf("cache contained zero items")
 	}
 	cache.Conventions = c.Items
-	cache.Age, err = s.client.db.CacheAge("conventions", "conventions", "json")
+	cache.Age, err = recordAge(s.client.db, r, CacheConventions, CacheConventions)
+	cache.TTL, _ = s.client.cachePolicies.TTL(CacheConventions, time.Now(), Convention{})
+	cache.Fresh = s.client.cachePolicies.Fresh(CacheConventions, cache.Age, Convention{})
 
 	return cache, err
 }
 
+// CachedConventions returns the conventions cached by the last
+// GetActiveConventions.
+func CachedConventions(db DB) (cz []Convention, err error) {
+	var c Conventions
+	c, _, err = readCachedConventions(db)
+	return c.Items, err
+}
+
+func readCachedConventions(db DB) (c Conventions, r Record, err error) {
+	r, err = readRecord(db, CacheConventions, CacheConventions, &c)
+	return c, r, err
+}
+
 func (s Session) GetActiveConventions() (cz []Convention, err error) {
-	cr, err := s.getConventionsByPage(1)
+	return s.GetActiveConventionsContext(context.Background())
+}
+
+// GetActiveConventionsContext fetches every convention and refreshes the
+// conventions cache.
+func (s Session) GetActiveConventionsContext(ctx context.Context) (cz []Convention, err error) {
+	cz, err = ListAll[Convention](ctx, s, "/api/convention", nil)
 	if err != nil {
 		return cz, err
 	}
-	if cr.Err != nil {
-		return cz, cr.Err
-	}
-	cz = append(cz, cr.Result.Items...)
-	if cr.Result.Paging == nil {
-		return cz, err
-	}
 
-	nextPage := cr.Result.Paging.NextPageNumber
-	pageCount := cr.Result.Paging.TotalPages
-	if pageCount == 0 {
-		return cz, err
-	}
-	for i := nextPage; i <= pageCount; i++ {
-		cr, _ = s.getConventionsByPage(int(i))
-		if cr.Err != nil {
-			return cz, fmt.Errorf("[%d]: (%s) %s", cr.Err.Code, cr.Err.Data, cr.Err.Message)
-		}
-		cz = append(cz, cr.Result.Items...)
-	}
 	c := &Conventions{Items: cz}
-	if b, e := json.Marshal(c); e == nil {
-		s.client.db.Store("conventions", "conventions", "json", b)
+	if e := storeRecord(s.client.db, CacheConventions, CacheConventions, s.client.sourceURL("/api/convention"), c); e != nil {
+		s.log.Warn("unable to cache conventions", "error", e)
 	}
 	return cz, err
 }
 
-func (s Session) getConventionsByPage(page int) (cr ConventionRespose, err error) {
-	params := map[string]string{
-		"session_id":      s.ID,
-		"_page_number":    fmt.Sprintf("%d", page),
-		"_items_per_page": "100",
-	}
-	var b []byte
-	b, err = s.client.httpGet("/api/convention", params, nil)
-	if err != nil {
-		return cr, err
-	}
-
-	err = json.Unmarshal(b, &cr)
-	if err != nil {
-		return cr, err
-	}
-	if cr.Err != nil {
-		return cr, cr.Err
-	}
-	return cr, err
-}
-
 type ConventionCache struct {
 	Conventions []Convention
 	Age         time.Duration
-}
-
-type ConventionRespose struct {
-	Result Conventions `json:"result"`
-	Err    *ApiError   `json:"error"`
+	// TTL is how long the cache stays fresh under its CachePolicy.
+	TTL   time.Duration
+	Fresh bool
 }
 
 type Conventions struct {
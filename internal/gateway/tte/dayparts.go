@@ -0,0 +1,192 @@
+package tte
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CacheDayparts is the id of the cached dayparts of a convention.
+const CacheDayparts = "dayparts"
+
+// Daypart is one of the time slots a convention schedules events into. Its
+// StartDate is wall clock time in the convention's time zone.
+type Daypart struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	DayName      string `json:"day_name"`
+	StartDate    string `json:"start_date"`
+	ConventionID string `json:"convention_id"`
+}
+
+type Dayparts struct {
+	Items []Daypart `json:"items"`
+}
+
+func (s Session) GetConventionDayparts(con Convention) (dz []Daypart, err error) {
+	return s.GetConventionDaypartsContext(context.Background(), con)
+}
+
+// GetConventionDaypartsContext returns the dayparts of con, from the cache
+// while its CachePolicy says it is fresh, or when they cannot be fetched.
+func (s Session) GetConventionDaypartsContext(ctx context.Context, con Convention) (dz []Daypart, err error) {
+	var cached Dayparts
+	r, cacheErr := readRecord(s.client.db, CacheDayparts, con.ViewURI, &cached)
+	if cacheErr == nil {
+		age, _ := recordAge(s.client.db, r, CacheDayparts, con.ViewURI)
+		if s.client.cachePolicies.Fresh(CacheDayparts, age, con) {
+			return cached.Items, nil
+		}
+	}
+
+	uri := fmt.Sprintf("/api/convention/%s/dayparts", con.ID)
+	dz, err = ListAll[Daypart](ctx, s, uri, nil)
+	if err != nil {
+		if cacheErr == nil && Unreachable(err) {
+			s.log.Warn("using stale dayparts", "convention", con.ViewURI, "error", err)
+			return cached.Items, nil
+		}
+		return dz, err
+	}
+	if e := storeRecord(s.client.db, CacheDayparts, con.ViewURI, s.client.sourceURL(uri), Dayparts{Items: dz}); e != nil {
+		s.log.Warn("unable to cache dayparts", "error", e)
+	}
+	return dz, nil
+}
+
+func (s Session) Timetable(con Convention, ez []ConventionEvent) (tt Timetable, err error) {
+	return s.TimetableContext(context.Background(), con, ez)
+}
+
+// TimetableContext resolves when each event of ez runs from the dayparts of
+// con, in the client's time zone. Without dayparts it still resolves what
+// it can from the events alone, and returns the error alongside.
+func (s Session) TimetableContext(ctx context.Context, con Convention, ez []ConventionEvent) (tt Timetable, err error) {
+	var dz []Daypart
+	dz, err = s.GetConventionDaypartsContext(ctx, con)
+	return NewTimetable(con, dz, ez, s.client.location), err
+}
+
+// EventTime is when an event runs.
+type EventTime struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Overlaps reports whether t and o share any time.
+func (t EventTime) Overlaps(o EventTime) bool {
+	return t.Start.Before(o.End) && o.Start.Before(t.End)
+}
+
+// Timetable holds when each event runs, by event id.
+type Timetable map[string]EventTime
+
+// NewTimetable resolves when each event of ez runs, in loc. An event starts
+// at its start daypart; an event whose daypart is unknown falls back to its
+// own start_date and then to the day and time in its daypart name. Events
+// that resolve to nothing are left out.
+func NewTimetable(con Convention, dz []Daypart, ez []ConventionEvent, loc *time.Location) (tt Timetable) {
+	if loc == nil {
+		loc = time.Local
+	}
+	dayparts := make(map[string]Daypart, len(dz))
+	for _, d := range dz {
+		dayparts[d.ID] = d
+	}
+	tt = make(Timetable, len(ez))
+	for _, e := range ez {
+		start, err := eventStart(con, dayparts, e, loc)
+		if err != nil {
+			continue
+		}
+		tt[e.ID] = EventTime{Start: start, End: start.Add(time.Duration(e.Duration) * time.Minute)}
+	}
+	return tt
+}
+
+func eventStart(con Convention, dayparts map[string]Daypart, e ConventionEvent, loc *time.Location) (start time.Time, err error) {
+	id := e.StartdaypartID
+	if len(id) == 0 && len(e.Relationships.Startdaypart) > 0 {
+		id = path.Base(e.Relationships.Startdaypart)
+	}
+	if d, ok := dayparts[id]; ok {
+		if start, err = parseTTEDate(d.StartDate, loc); err == nil {
+			return start, nil
+		}
+	}
+	if len(e.StartDate) > 0 {
+		if start, err = parseTTEDate(e.StartDate, loc); err == nil {
+			return start, nil
+		}
+	}
+	return e.StartdaypartName.Resolve(con, loc)
+}
+
+// Get returns when e runs.
+func (tt Timetable) Get(e ConventionEvent) (t EventTime, ok bool) {
+	t, ok = tt[e.ID]
+	return t, ok
+}
+
+// Sort orders ez by start time, then name. Events without a time go last.
+func (tt Timetable) Sort(ez []ConventionEvent) {
+	sort.SliceStable(ez, func(i, j int) bool {
+		a, aok := tt[ez[i].ID]
+		b, bok := tt[ez[j].ID]
+		switch {
+		case aok != bok:
+			return aok
+		case aok && !a.Start.Equal(b.Start):
+			return a.Start.Before(b.Start)
+		}
+		return ez[i].Name < ez[j].Name
+	})
+}
+
+// Format shows t as e.g. "Thu Jul 31 9:00 AM - 11:00 AM".
+func (t EventTime) Format() string {
+	end := t.End.Format("3:04 PM")
+	if t.End.YearDay() != t.Start.YearDay() {
+		end = t.End.Format("Mon 3:04 PM")
+	}
+	return fmt.Sprintf("%s - %s", t.Start.Format("Mon Jan 2 3:04 PM"), end)
+}
+
+// Resolve finds the first day of con falling on the weekday named in dt and
+// returns dt's time of day on it, in loc.
+func (dt Daytime) Resolve(con Convention, loc *time.Location) (t time.Time, err error) {
+	day, clock, ok := dt.parts()
+	if !ok {
+		return t, fmt.Errorf("unrecognized daytime %q", dt)
+	}
+	weekday, ok := weekdays[strings.ToLower(day)]
+	if !ok {
+		return t, fmt.Errorf("unrecognized day in %q", dt)
+	}
+	tod, err := time.Parse("3:04 PM", clock)
+	if err != nil {
+		return t, fmt.Errorf("unrecognized time in %q: %w", dt, err)
+	}
+	first, err := parseTTEDate(con.StartDate, loc)
+	if err != nil {
+		return t, err
+	}
+	first = time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc)
+	for first.Weekday() != weekday {
+		first = first.AddDate(0, 0, 1)
+	}
+	return time.Date(first.Year(), first.Month(), first.Day(), tod.Hour(), tod.Minute(), 0, 0, loc), nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
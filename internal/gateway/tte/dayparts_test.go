@@ -0,0 +1,70 @@
+package tte
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimetableResolvesEventTimes(t *testing.T) {
+	loc := time.FixedZone("con", -4*60*60)
+	con := Convention{ID: "con-1", ViewURI: "/convention/test-con", StartDate: "2025-07-31 08:00:00", EndDate: "2025-08-03"}
+	dz := []Daypart{
+		{ID: "dp-thu-9", Name: "Thursday at  9:00 AM", StartDate: "2025-07-31 09:00:00"},
+		{ID: "dp-fri-9", Name: "Friday at  9:00 AM", StartDate: "2025-08-01 09:00:00"},
+	}
+	ez := []ConventionEvent{
+		{ID: "by-name", Name: "A", StartdaypartName: "Saturday at  1:00 PM", Duration: 60},
+		{ID: "by-id", Name: "B", StartdaypartID: "dp-fri-9", StartdaypartName: "Friday at  9:00 AM", Duration: 240},
+		{ID: "by-uri", Name: "C", Relationships: ConventionEventRelationships{Startdaypart: "/api/daypart/dp-thu-9"}, Duration: 120},
+		{ID: "by-date", Name: "D", StartDate: "2025-07-31 08:30:00", Duration: 30},
+		{ID: "unknown", Name: "E", StartdaypartName: "TBD"},
+	}
+
+	tt := NewTimetable(con, dz, ez, loc)
+	want := map[string]string{
+		"by-id":   "2025-08-01T09:00:00-04:00",
+		"by-uri":  "2025-07-31T09:00:00-04:00",
+		"by-date": "2025-07-31T08:30:00-04:00",
+		"by-name": "2025-08-02T13:00:00-04:00",
+	}
+	for id, start := range want {
+		if got := tt[id].Start.Format(time.RFC3339); got != start {
+			t.Errorf("%s starts %s, want %s", id, got, start)
+		}
+	}
+	if _, ok := tt["unknown"]; ok {
+		t.Error("unresolvable event has a time")
+	}
+	if end := tt["by-id"].End; !end.Equal(tt["by-id"].Start.Add(4 * time.Hour)) {
+		t.Errorf("by-id ends %s", end)
+	}
+
+	tt.Sort(ez)
+	var order string
+	for _, e := range ez {
+		order += e.Name
+	}
+	if order != "DCBAE" {
+		t.Errorf("sorted %s, want DCBAE", order)
+	}
+}
+
+func TestGetConventionDaypartsFallsBackToCache(t *testing.T) {
+	srv := newFakeTTE(t, 0)
+	db := NewMemoryDB()
+	con := Convention{ID: "con-1", ViewURI: "/convention/test-con"}
+	s := Session{id: "sess-1", client: NewClient(quietLog, db, "test-key", WithBaseURL(srv.URL)), log: quietLog}
+
+	dz, err := s.GetConventionDayparts(con)
+	if err != nil || len(dz) != 1 || dz[0].ID != "dp-fri-9" {
+		t.Fatalf("dayparts = %+v, %v", dz, err)
+	}
+
+	// stale and offline, the cached dayparts still serve
+	off := NewOfflineClient(quietLog, db, WithHTTPCache(false), WithCachePolicies(CachePolicies{
+		CacheDayparts: {Kind: CacheDayparts, TTL: func(time.Time, Convention) time.Duration { return 0 }},
+	})).OfflineSession()
+	if dz, err = off.GetConventionDayparts(con); err != nil || len(dz) != 1 {
+		t.Fatalf("offline dayparts = %+v, %v", dz, err)
+	}
+}
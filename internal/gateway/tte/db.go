@@ -1,65 +1,147 @@
 package tte
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
+)
+
+// DB stores records addressed by an id, a kind and a data type.
+type DB interface {
+	Store(id string, kind string, dataType string, data []byte) error
+	// Read returns an error matching fs.ErrNotExist for a missing record.
+	Read(id string, kind string, dataType string) ([]byte, error)
+	CacheAge(id string, kind string, dataType string) (time.Duration, error)
+	// Delete succeeds for a missing record.
+	Delete(id string, kind string, dataType string) error
+	// List returns the keys of every record of kind, including those of
+	// kinds nested below it. An empty kind lists the whole DB.
+	List(kind string) ([]Key, error)
+}
+
+// ErrCorruptRecord is returned by Read for a record that was damaged, e.g.
+// by an interrupted write, and has no intact copy to fall back on.
+var ErrCorruptRecord = errors.New("cache record is corrupt")
 
-	"github.com/dan-frohlich/tabetopevents/internal/logging"
+var (
+	_ DB = FileDB{}
+	_ DB = (*MemoryDB)(nil)
+	_ DB = (*SingleFileDB)(nil)
+	_ DB = prefixDB{}
 )
 
-type DB struct {
-	path string
-	log  logging.Logger
+// Key identifies a record in a DB.
+type Key struct {
+	ID       string
+	Kind     string
+	DataType string
 }
 
-func NewDB(log logging.Logger) DB {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		log.Error("could not locate user home dir", "error", err)
-		home = "."
-	}
-	newpath := filepath.Join(home, ".tte_db")
-	_ = os.MkdirAll(newpath, os.ModePerm)
+func (k Key) String() string {
+	return path.Join(k.Kind, fmt.Sprintf("%s.%s", k.ID, k.DataType))
+}
 
-	return DB{path: newpath, log: log}
+func newKey(id string, kind string, dataType string) Key {
+	return Key{ID: id, Kind: cleanKind(kind), DataType: dataType}
 }
 
-func (db DB) mkdir(kind string) {
-	_ = os.MkdirAll(db.kindPath(kind), os.ModePerm)
+// cleanKind normalizes kind so "/convention/x" and "convention/x" name the
+// same records in every DB.
+func cleanKind(kind string) string {
+	kind = strings.Trim(path.Clean("/"+kind), "/")
+	return kind
 }
 
-func (db DB) kindPath(kind string) (path string) {
-	return filepath.Join(db.path, kind)
+// inKind reports whether a record of kind k is listed under kind.
+func inKind(k string, kind string) bool {
+	return len(kind) == 0 || k == kind || strings.HasPrefix(k, kind+"/")
 }
 
-func (db DB) itemPath(id string, kind string, dataType string) (path string) {
-	return filepath.Join(db.path, kind, fmt.Sprintf("%s.%s", id, dataType))
+func notCached(k Key) error {
+	return fmt.Errorf("unable to load %s : %w", k, fs.ErrNotExist)
 }
 
-func (db DB) Store(id string, kind string, dataType string, data []byte) error {
-	db.mkdir(kind)
-	filePath := db.itemPath(id, kind, dataType)
-	db.log.Debug("writing cache", "path", filePath)
-	return os.WriteFile(filePath, data, os.FileMode(0644))
+func sortKeys(keys []Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
 }
 
-func (db DB) Read(id string, kind string, dataType string) (data []byte, err error) {
-	filePath := db.itemPath(id, kind, dataType)
-	db.log.Debug("reading cache", "path", filePath)
-	data, err = os.ReadFile(filePath)
-	if err != nil {
-		err = fmt.Errorf("unable to load %s : %s", filePath, err)
+// DefaultProfile is the profile used when none is chosen. It lives at the
+// root of the DB, where buddy kept everything before there were profiles.
+const DefaultProfile = "default"
+
+const profilesKind = "profiles"
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ProfileDB returns the part of db holding the credentials, session, likes
+// and other per-account data of the named profile. Convention, event and
+// event type caches are shared and stay in db.
+func ProfileDB(db DB, name string) (DB, error) {
+	if len(name) == 0 || name == DefaultProfile {
+		return db, nil
 	}
-	return data, err
+	if !profileNamePattern.MatchString(name) {
+		return db, fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return prefixDB{db: db, prefix: path.Join(profilesKind, name)}, nil
 }
 
-func (db DB) CacheAge(id string, kind string, dataType string) (time.Duration, error) {
-	fileInfo, err := os.Stat(db.itemPath(id, kind, dataType))
+// Profiles lists the named profiles that hold data, plus the default.
+func Profiles(db DB) (names []string, err error) {
+	names = append(names, DefaultProfile)
+	keys, err := db.List(profilesKind)
 	if err != nil {
-		return 0, err
+		return names, err
+	}
+	seen := map[string]bool{}
+	for _, k := range keys {
+		name, _, _ := strings.Cut(strings.TrimPrefix(k.Kind, profilesKind+"/"), "/")
+		if len(name) > 0 && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names, nil
+}
+
+// prefixDB nests every kind of a DB under a prefix.
+type prefixDB struct {
+	db     DB
+	prefix string
+}
+
+func (p prefixDB) kind(kind string) string {
+	return path.Join(p.prefix, cleanKind(kind))
+}
+
+func (p prefixDB) Store(id string, kind string, dataType string, data []byte) error {
+	return p.db.Store(id, p.kind(kind), dataType, data)
+}
+
+func (p prefixDB) Read(id string, kind string, dataType string) ([]byte, error) {
+	return p.db.Read(id, p.kind(kind), dataType)
+}
+
+func (p prefixDB) CacheAge(id string, kind string, dataType string) (time.Duration, error) {
+	return p.db.CacheAge(id, p.kind(kind), dataType)
+}
+
+func (p prefixDB) Delete(id string, kind string, dataType string) error {
+	return p.db.Delete(id, p.kind(kind), dataType)
+}
+
+func (p prefixDB) List(kind string) (keys []Key, err error) {
+	keys, err = p.db.List(p.kind(kind))
+	for i := range keys {
+		keys[i].Kind = strings.TrimPrefix(strings.TrimPrefix(keys[i].Kind, p.prefix), "/")
 	}
-	modTime := fileInfo.ModTime()
-	return time.Since(modTime).Truncate(time.Second), nil
+	return keys, err
 }
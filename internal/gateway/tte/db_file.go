@@ -0,0 +1,273 @@
+package tte
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dan-frohlich/tabetopevents/internal/logging"
+)
+
+// the db holds credentials, so only its owner may read it
+const (
+	dirMode  os.FileMode = 0700
+	fileMode os.FileMode = 0600
+)
+
+// FileDB keeps each record in its own file, <root>/<kind>/<id>.<dataType>,
+// with every part passed through encodeKeyPart so that URIs used as ids or
+// kinds cannot nest directories or escape the root.
+type FileDB struct {
+	path string
+	log  logging.Logger
+}
+
+// NewDB opens the FileDB in ~/.tte_db.
+func NewDB(log logging.Logger) FileDB {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Error("could not locate user home dir", "error", err)
+		home = "."
+	}
+	return NewFileDB(log, filepath.Join(home, ".tte_db"))
+}
+
+func NewFileDB(log logging.Logger, path string) FileDB {
+	_ = os.MkdirAll(path, dirMode)
+	db := FileDB{path: path, log: log}
+	if err := db.migrateLayout(); err != nil {
+		log.Error("failed to migrate cache layout", "path", path, "error", err)
+	}
+	return db
+}
+
+func (db FileDB) mkdir(kind string) {
+	_ = os.MkdirAll(db.kindPath(kind), dirMode)
+}
+
+func (db FileDB) kindPath(kind string) (path string) {
+	return filepath.Join(db.path, encodeKeyPart(cleanKind(kind)))
+}
+
+func (db FileDB) itemPath(id string, kind string, dataType string) (path string) {
+	return filepath.Join(db.kindPath(kind), encodeFileName(id, dataType))
+}
+
+// lock takes the DB-wide advisory lock that serializes buddy processes.
+func (db FileDB) lock(exclusive bool) (unlock func()) {
+	unlock, err := lockFile(filepath.Join(db.path, ".lock"), exclusive)
+	if err != nil {
+		db.log.Warn("unable to lock cache, continuing without it", "error", err)
+		return func() {}
+	}
+	return unlock
+}
+
+// sidecar files live beside a record with a leading '.', which never starts
+// an encoded name, so List does not see them
+func sumPath(filePath string) string {
+	return filepath.Join(filepath.Dir(filePath), "."+filepath.Base(filePath)+".sum")
+}
+
+func backupPath(filePath string) string {
+	return filepath.Join(filepath.Dir(filePath), "."+filepath.Base(filePath)+".bak")
+}
+
+// intact reports whether data read from filePath matches its checksum.
+// Records written before checksums existed are accepted when they at least
+// parse as json, if they claim to be json.
+func intact(filePath string, dataType string, data []byte) bool {
+	sum, err := os.ReadFile(sumPath(filePath))
+	if err == nil {
+		return string(sum) == checksum(data)
+	}
+	if dataType == "json" {
+		return json.Valid(data)
+	}
+	return true
+}
+
+// Store writes the record atomically, keeping the previous intact copy as
+// a backup for Read to fall back on.
+func (db FileDB) Store(id string, kind string, dataType string, data []byte) error {
+	unlock := db.lock(true)
+	defer unlock()
+
+	db.mkdir(kind)
+	filePath := db.itemPath(id, kind, dataType)
+	db.log.Debug("writing cache", "path", filePath)
+
+	if current, err := os.ReadFile(filePath); err == nil && intact(filePath, dataType, current) {
+		bak := backupPath(filePath)
+		_ = os.Remove(sumPath(bak))
+		if err = os.Rename(filePath, bak); err != nil {
+			return err
+		}
+		_ = os.Rename(sumPath(filePath), sumPath(bak))
+	}
+	if err := writeFileAtomic(filePath, data); err != nil {
+		return err
+	}
+	return writeFileAtomic(sumPath(filePath), []byte(checksum(data)))
+}
+
+// Read returns the record, or its last intact copy when the record is
+// missing its latest write or fails its checksum.
+func (db FileDB) Read(id string, kind string, dataType string) (data []byte, err error) {
+	unlock := db.lock(false)
+	defer unlock()
+
+	filePath := db.itemPath(id, kind, dataType)
+	db.log.Debug("reading cache", "path", filePath)
+	data, err = os.ReadFile(filePath)
+	if err == nil && intact(filePath, dataType, data) {
+		return data, nil
+	}
+
+	bak := backupPath(filePath)
+	if b, e := os.ReadFile(bak); e == nil && intact(bak, dataType, b) {
+		db.log.Warn("cache record damaged, using its last good copy", "path", filePath)
+		return b, nil
+	}
+	if err == nil {
+		err = ErrCorruptRecord
+	}
+	return nil, fmt.Errorf("unable to load %s : %w", filePath, err)
+}
+
+func (db FileDB) CacheAge(id string, kind string, dataType string) (time.Duration, error) {
+	fileInfo, err := os.Stat(db.itemPath(id, kind, dataType))
+	if err != nil {
+		return 0, err
+	}
+	modTime := fileInfo.ModTime()
+	return time.Since(modTime).Truncate(time.Second), nil
+}
+
+func (db FileDB) Delete(id string, kind string, dataType string) error {
+	unlock := db.lock(true)
+	defer unlock()
+
+	filePath := db.itemPath(id, kind, dataType)
+	db.log.Debug("deleting cache", "path", filePath)
+	var err error
+	for _, p := range []string{filePath, sumPath(filePath), backupPath(filePath), sumPath(backupPath(filePath))} {
+		if e := os.Remove(p); e != nil && !errors.Is(e, fs.ErrNotExist) {
+			err = errors.Join(err, e)
+		}
+	}
+	return err
+}
+
+func (db FileDB) List(kind string) (keys []Key, err error) {
+	kind = cleanKind(kind)
+	dirs, err := os.ReadDir(db.path)
+	if err != nil {
+		return keys, err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || strings.HasPrefix(dir.Name(), ".") {
+			continue
+		}
+		k, e := decodeKeyPart(dir.Name())
+		if e != nil || !inKind(k, kind) {
+			continue
+		}
+		files, e := os.ReadDir(filepath.Join(db.path, dir.Name()))
+		if e != nil {
+			err = errors.Join(err, e)
+			continue
+		}
+		for _, f := range files {
+			if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+				continue
+			}
+			id, dataType, e := decodeFileName(f.Name())
+			if e != nil {
+				continue
+			}
+			keys = append(keys, Key{ID: id, Kind: k, DataType: dataType})
+		}
+	}
+	sortKeys(keys)
+	return keys, err
+}
+
+// fileDBLayout versions the on disk layout. Version 1 joined raw ids and
+// kinds into paths; version 2 encodes them.
+const (
+	fileDBLayout       = "2"
+	fileDBLayoutMarker = ".layout"
+)
+
+// migrateLayout moves records written with raw, nested paths by older
+// versions of buddy to their encoded paths. It runs once per DB.
+func (db FileDB) migrateLayout() error {
+	unlock := db.lock(true)
+	defer unlock()
+
+	marker := filepath.Join(db.path, fileDBLayoutMarker)
+	if b, err := os.ReadFile(marker); err == nil && string(b) == fileDBLayout {
+		return nil
+	}
+
+	var legacy []string
+	err := filepath.WalkDir(db.path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !strings.HasPrefix(d.Name(), ".") {
+			legacy = append(legacy, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range legacy {
+		rel, err := filepath.Rel(db.path, p)
+		if err != nil {
+			return err
+		}
+		dir, file := filepath.Split(rel)
+		i := strings.LastIndex(file, ".")
+		if i <= 0 || len(dir) == 0 {
+			continue
+		}
+		to := db.itemPath(file[:i], filepath.ToSlash(dir), file[i+1:])
+		if to == p {
+			continue
+		}
+		if err = os.MkdirAll(filepath.Dir(to), dirMode); err != nil {
+			return err
+		}
+		if err = os.Rename(p, to); err != nil {
+			return err
+		}
+		db.log.Debug("migrated cache record", "from", p, "to", to)
+	}
+	db.removeEmptyDirs()
+
+	return os.WriteFile(marker, []byte(fileDBLayout), fileMode)
+}
+
+// removeEmptyDirs prunes the directories left empty by migrateLayout.
+func (db FileDB) removeEmptyDirs() {
+	var dirs []string
+	_ = filepath.WalkDir(db.path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && p != db.path {
+			dirs = append(dirs, p)
+		}
+		return nil
+	})
+	// deepest first, so parents are empty by the time they are tried
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i])
+	}
+}
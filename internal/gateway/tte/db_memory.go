@@ -0,0 +1,75 @@
+package tte
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryDB keeps records in memory. It suits tests and short lived
+// embedded use.
+type MemoryDB struct {
+	mu      sync.Mutex
+	records map[string]dbRecord
+}
+
+// dbRecord is a record as kept by the MemoryDB and the SingleFileDB.
+type dbRecord struct {
+	Key      Key
+	Data     []byte
+	Modified time.Time
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{records: map[string]dbRecord{}}
+}
+
+func (db *MemoryDB) Store(id string, kind string, dataType string, data []byte) error {
+	k := newKey(id, kind, dataType)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.records[k.String()] = dbRecord{Key: k, Data: append([]byte(nil), data...), Modified: time.Now()}
+	return nil
+}
+
+func (db *MemoryDB) Read(id string, kind string, dataType string) ([]byte, error) {
+	k := newKey(id, kind, dataType)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	r, ok := db.records[k.String()]
+	if !ok {
+		return nil, notCached(k)
+	}
+	return append([]byte(nil), r.Data...), nil
+}
+
+func (db *MemoryDB) CacheAge(id string, kind string, dataType string) (time.Duration, error) {
+	k := newKey(id, kind, dataType)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	r, ok := db.records[k.String()]
+	if !ok {
+		return 0, notCached(k)
+	}
+	return time.Since(r.Modified).Truncate(time.Second), nil
+}
+
+func (db *MemoryDB) Delete(id string, kind string, dataType string) error {
+	k := newKey(id, kind, dataType)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	delete(db.records, k.String())
+	return nil
+}
+
+func (db *MemoryDB) List(kind string) (keys []Key, err error) {
+	kind = cleanKind(kind)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, r := range db.records {
+		if inKind(r.Key.Kind, kind) {
+			keys = append(keys, r.Key)
+		}
+	}
+	sortKeys(keys)
+	return keys, nil
+}
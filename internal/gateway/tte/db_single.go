@@ -0,0 +1,143 @@
+package tte
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SingleFileDB keeps every record in one file, for embedding the DB where a
+// directory tree is unwelcome. The file is read for every call and rewritten
+// for every change, so it suits the modest size of buddy's data.
+type SingleFileDB struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewSingleFileDB(path string) (*SingleFileDB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+		return nil, err
+	}
+	return &SingleFileDB{path: path}, nil
+}
+
+func (db *SingleFileDB) load() (records map[string]dbRecord, err error) {
+	records = map[string]dbRecord{}
+	b, err := os.ReadFile(db.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return records, err
+	}
+	err = gob.NewDecoder(bytes.NewReader(b)).Decode(&records)
+	return records, err
+}
+
+func (db *SingleFileDB) save(records map[string]dbRecord) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(records); err != nil {
+		return err
+	}
+	return writeFileAtomic(db.path, buf.Bytes())
+}
+
+// lock serializes processes sharing the file; load and save must be called
+// while holding it.
+func (db *SingleFileDB) lock(exclusive bool) (unlock func(), err error) {
+	db.mu.Lock()
+	fileUnlock, err := lockFile(db.path+".lock", exclusive)
+	if err != nil {
+		db.mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		fileUnlock()
+		db.mu.Unlock()
+	}, nil
+}
+
+func (db *SingleFileDB) Store(id string, kind string, dataType string, data []byte) error {
+	k := newKey(id, kind, dataType)
+	unlock, err := db.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	records, err := db.load()
+	if err != nil {
+		return err
+	}
+	records[k.String()] = dbRecord{Key: k, Data: data, Modified: time.Now()}
+	return db.save(records)
+}
+
+func (db *SingleFileDB) record(k Key) (r dbRecord, err error) {
+	unlock, err := db.lock(false)
+	if err != nil {
+		return r, err
+	}
+	defer unlock()
+	records, err := db.load()
+	if err != nil {
+		return r, err
+	}
+	r, ok := records[k.String()]
+	if !ok {
+		return r, notCached(k)
+	}
+	return r, nil
+}
+
+func (db *SingleFileDB) Read(id string, kind string, dataType string) ([]byte, error) {
+	r, err := db.record(newKey(id, kind, dataType))
+	return r.Data, err
+}
+
+func (db *SingleFileDB) CacheAge(id string, kind string, dataType string) (time.Duration, error) {
+	r, err := db.record(newKey(id, kind, dataType))
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(r.Modified).Truncate(time.Second), nil
+}
+
+func (db *SingleFileDB) Delete(id string, kind string, dataType string) error {
+	k := newKey(id, kind, dataType)
+	unlock, err := db.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	records, err := db.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := records[k.String()]; !ok {
+		return nil
+	}
+	delete(records, k.String())
+	return db.save(records)
+}
+
+func (db *SingleFileDB) List(kind string) (keys []Key, err error) {
+	kind = cleanKind(kind)
+	unlock, err := db.lock(false)
+	if err != nil {
+		return keys, err
+	}
+	defer unlock()
+	records, err := db.load()
+	for _, r := range records {
+		if inKind(r.Key.Kind, kind) {
+			keys = append(keys, r.Key)
+		}
+	}
+	sortKeys(keys)
+	return keys, err
+}
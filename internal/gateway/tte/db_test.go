@@ -0,0 +1,169 @@
+package tte
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDBImplementations(t *testing.T) {
+	newDBs := map[string]func(t *testing.T) DB{
+		"file":   func(t *testing.T) DB { return NewFileDB(quietLog, t.TempDir()) },
+		"memory": func(t *testing.T) DB { return NewMemoryDB() },
+		"single": func(t *testing.T) DB {
+			db, err := NewSingleFileDB(filepath.Join(t.TempDir(), "tte.db"))
+			if err != nil {
+				t.Fatalf("NewSingleFileDB: %v", err)
+			}
+			return db
+		},
+		"profile": func(t *testing.T) DB {
+			db, err := ProfileDB(NewMemoryDB(), "gm")
+			if err != nil {
+				t.Fatalf("ProfileDB: %v", err)
+			}
+			return db
+		},
+	}
+	for name, newDB := range newDBs {
+		t.Run(name, func(t *testing.T) {
+			db := newDB(t)
+			if _, err := db.Read("events", "/convention/test-con", "json"); !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("Read of a missing record = %v, want fs.ErrNotExist", err)
+			}
+			if err := db.Store("events", "/convention/test-con", "json", []byte(`{"items":[]}`)); err != nil {
+				t.Fatalf("Store: %v", err)
+			}
+			if err := db.Store("apikey", "client", "txt", []byte("key")); err != nil {
+				t.Fatalf("Store: %v", err)
+			}
+			b, err := db.Read("events", "/convention/test-con", "json")
+			if err != nil || string(b) != `{"items":[]}` {
+				t.Fatalf("Read = %q, %v", b, err)
+			}
+			if _, err = db.CacheAge("events", "/convention/test-con", "json"); err != nil {
+				t.Fatalf("CacheAge: %v", err)
+			}
+
+			keys, err := db.List("convention")
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			want := Key{ID: "events", Kind: "convention/test-con", DataType: "json"}
+			if len(keys) != 1 || keys[0] != want {
+				t.Fatalf("List(convention) = %v, want [%v]", keys, want)
+			}
+			if keys, _ = db.List(""); len(keys) != 2 {
+				t.Fatalf("List() = %v, want 2 keys", keys)
+			}
+
+			if err = db.Delete("events", "/convention/test-con", "json"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if err = db.Delete("events", "/convention/test-con", "json"); err != nil {
+				t.Fatalf("Delete of a missing record: %v", err)
+			}
+			if _, err = db.Read("events", "/convention/test-con", "json"); err == nil {
+				t.Fatal("record survived Delete")
+			}
+		})
+	}
+}
+
+func TestFileDBMigratesLegacyLayout(t *testing.T) {
+	root := t.TempDir()
+	legacy := filepath.Join(root, "convention", "gen-con-2025", "events.json")
+	if err := os.MkdirAll(filepath.Dir(legacy), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(legacy, []byte(`{"items":[]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	db := NewFileDB(quietLog, root)
+	b, err := db.Read("events", "/convention/gen-con-2025", "json")
+	if err != nil || string(b) != `{"items":[]}` {
+		t.Fatalf("Read after migration = %q, %v", b, err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "convention")); !os.IsNotExist(err) {
+		t.Fatalf("legacy directory left behind: %v", err)
+	}
+}
+
+func TestFileDBStaysInsideRoot(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "db")
+	db := NewFileDB(quietLog, root)
+	if err := db.Store("../../escape", "../..", "txt", []byte("x")); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	entries, _ := os.ReadDir(parent)
+	if len(entries) != 1 || entries[0].Name() != "db" {
+		t.Fatalf("Store wrote outside the db root: %v", entries)
+	}
+	keys, err := db.List("")
+	if err != nil || len(keys) != 1 || keys[0].ID != "../../escape" {
+		t.Fatalf("List = %v, %v", keys, err)
+	}
+}
+
+func TestFileDBFallsBackToLastGoodCopy(t *testing.T) {
+	db := NewFileDB(quietLog, t.TempDir())
+	if err := db.Store("liked", "convention/test-con", "txt", []byte("/event/a\n/event/b")); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := db.Store("liked", "convention/test-con", "txt", []byte("/event/a\n/event/b\n/event/c")); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	// simulate a write cut short by ctrl-c
+	if err := os.WriteFile(db.itemPath("liked", "convention/test-con", "txt"), []byte("/event/a\n/ev"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	b, err := db.Read("liked", "convention/test-con", "txt")
+	if err != nil || string(b) != "/event/a\n/event/b" {
+		t.Fatalf("Read = %q, %v; want the last good copy", b, err)
+	}
+
+	if err = db.Delete("liked", "convention/test-con", "txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err = db.Store("events", "convention/test-con", "json", []byte(`{"items":[`)); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err = os.Remove(sumPath(db.itemPath("events", "convention/test-con", "json"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Read("events", "convention/test-con", "json"); !errors.Is(err, ErrCorruptRecord) {
+		t.Fatalf("Read of truncated json = %v, want ErrCorruptRecord", err)
+	}
+}
+
+func TestFileDBConcurrentStores(t *testing.T) {
+	db := NewFileDB(quietLog, t.TempDir())
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data := []byte(strings.Repeat(fmt.Sprintf("%02d", i), 4096))
+			if err := db.Store("events", "convention/test-con", "txt", data); err != nil {
+				t.Errorf("Store: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	b, err := db.Read("events", "convention/test-con", "txt")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(b) != 8192 || strings.Count(string(b), string(b[:2])) != 4096 {
+		t.Fatalf("record mixes concurrent writes: %q...", b[:16])
+	}
+}
@@ -0,0 +1,135 @@
+package tte
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChangeKind names what changed about an event between two snapshots.
+type ChangeKind string
+
+const (
+	EventAdded       ChangeKind = "added"
+	EventRemoved     ChangeKind = "removed"
+	EventCancelled   ChangeKind = "cancelled"
+	EventReinstated  ChangeKind = "reinstated"
+	EventRescheduled ChangeKind = "time"
+	EventMoved       ChangeKind = "room"
+	EventEdited      ChangeKind = "description"
+	EventSeats       ChangeKind = "seats"
+)
+
+// changeOrder lists the kinds of change of one event most important first.
+var changeOrder = map[ChangeKind]int{
+	EventAdded:       0,
+	EventRemoved:     1,
+	EventCancelled:   2,
+	EventReinstated:  3,
+	EventRescheduled: 4,
+	EventMoved:       5,
+	EventEdited:      6,
+	EventSeats:       7,
+}
+
+// EventChange is one change to an event. Event is its newer version, or the
+// last one seen for a removed event.
+type EventChange struct {
+	Kind  ChangeKind
+	Event ConventionEvent
+	From  string
+	To    string
+}
+
+func (c EventChange) String() string {
+	name := fmt.Sprintf("#%d %s", c.Event.EventNumber, c.Event.Name)
+	switch c.Kind {
+	case EventAdded, EventRemoved, EventCancelled, EventReinstated, EventEdited:
+		return fmt.Sprintf("%s: %s", c.Kind, name)
+	}
+	return fmt.Sprintf("%s: %s: %s -> %s", c.Kind, name, c.From, c.To)
+}
+
+// EventChanges are the changes between two snapshots, ordered by event
+// number.
+type EventChanges []EventChange
+
+// Affecting returns the changes to the events with the given view uris.
+func (cz EventChanges) Affecting(viewURIs ...string) (affecting EventChanges) {
+	set := make(map[string]struct{}, len(viewURIs))
+	for _, uri := range viewURIs {
+		set[uri] = struct{}{}
+	}
+	for _, c := range cz {
+		if _, ok := set[c.Event.ViewURI]; ok {
+			affecting = append(affecting, c)
+		}
+	}
+	return affecting
+}
+
+// DiffEvents reports how the events in newer differ from those in older.
+func DiffEvents(older []ConventionEvent, newer []ConventionEvent) (changes EventChanges) {
+	before := make(map[string]ConventionEvent, len(older))
+	for _, e := range older {
+		before[e.ID] = e
+	}
+	seen := make(map[string]struct{}, len(newer))
+	for _, e := range newer {
+		seen[e.ID] = struct{}{}
+		o, ok := before[e.ID]
+		if !ok {
+			changes = append(changes, EventChange{Kind: EventAdded, Event: e})
+			continue
+		}
+		changes = append(changes, diffEvent(o, e)...)
+	}
+	for _, o := range older {
+		if _, ok := seen[o.ID]; !ok {
+			changes = append(changes, EventChange{Kind: EventRemoved, Event: o})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Event.EventNumber != changes[j].Event.EventNumber {
+			return changes[i].Event.EventNumber < changes[j].Event.EventNumber
+		}
+		return changeOrder[changes[i].Kind] < changeOrder[changes[j].Kind]
+	})
+	return changes
+}
+
+func diffEvent(o ConventionEvent, e ConventionEvent) (changes EventChanges) {
+	change := func(kind ChangeKind, from string, to string) {
+		changes = append(changes, EventChange{Kind: kind, Event: e, From: from, To: to})
+	}
+	switch {
+	case o.IsCancelled == 0 && e.IsCancelled != 0:
+		change(EventCancelled, "", "")
+	case o.IsCancelled != 0 && e.IsCancelled == 0:
+		change(EventReinstated, "", "")
+	}
+	if from, to := eventTime(o), eventTime(e); from != to {
+		change(EventRescheduled, from, to)
+	}
+	if from, to := eventRoom(o), eventRoom(e); from != to {
+		change(EventMoved, from, to)
+	}
+	if o.Description != e.Description || o.LongDescription != e.LongDescription {
+		change(EventEdited, o.Description, e.Description)
+	}
+	if o.AvailableQuantity != e.AvailableQuantity {
+		change(EventSeats, fmt.Sprint(o.AvailableQuantity), fmt.Sprint(e.AvailableQuantity))
+	}
+	return changes
+}
+
+func eventTime(e ConventionEvent) string {
+	return fmt.Sprintf("%s (%dm)", strings.Join(strings.Fields(string(e.StartdaypartName)), " "), e.Duration)
+}
+
+func eventRoom(e ConventionEvent) string {
+	if len(e.SpaceName) == 0 {
+		return e.RoomName
+	}
+	return fmt.Sprintf("%s / %s", e.RoomName, e.SpaceName)
+}
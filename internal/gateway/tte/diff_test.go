@@ -0,0 +1,77 @@
+package tte
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffEvents(t *testing.T) {
+	base := ConventionEvent{ID: "1", EventNumber: 1, Name: "Catan", ViewURI: "/event/catan",
+		StartdaypartName: "Friday at 10:00 AM", Duration: 120, RoomName: "Hall A", AvailableQuantity: 4, Description: "trade"}
+	gone := ConventionEvent{ID: "2", EventNumber: 2, Name: "Gloomhaven", ViewURI: "/event/gloom"}
+	added := ConventionEvent{ID: "3", EventNumber: 3, Name: "Azul", ViewURI: "/event/azul"}
+
+	changed := base
+	changed.StartdaypartName = "Friday at  2:00 PM"
+	changed.RoomName = "Hall B"
+	changed.AvailableQuantity = 0
+	changed.Description = "trade and build"
+	changed.IsCancelled = 1
+
+	changes := DiffEvents([]ConventionEvent{base, gone}, []ConventionEvent{changed, added})
+	want := []ChangeKind{EventCancelled, EventRescheduled, EventMoved, EventEdited, EventSeats, EventRemoved, EventAdded}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %v", changes)
+	}
+	for i, kind := range want {
+		if changes[i].Kind != kind {
+			t.Errorf("change %d = %s, want %s", i, changes[i], kind)
+		}
+	}
+	if c := changes[1]; c.From != "Friday at 10:00 AM (120m)" || c.To != "Friday at 2:00 PM (120m)" {
+		t.Errorf("time change = %s", c)
+	}
+	if c := changes[4]; c.From != "4" || c.To != "0" {
+		t.Errorf("seats change = %s", c)
+	}
+
+	if liked := changes.Affecting("/event/gloom"); len(liked) != 1 || liked[0].Kind != EventRemoved {
+		t.Errorf("affecting = %v", liked)
+	}
+	if same := DiffEvents([]ConventionEvent{base}, []ConventionEvent{base}); len(same) != 0 {
+		t.Errorf("unchanged events diff = %v", same)
+	}
+}
+
+func TestSnapshotsAreKeptAndPruned(t *testing.T) {
+	db := NewMemoryDB()
+	con := Convention{ViewURI: "/convention/test-con"}
+	_ = db.Store(CacheEvents, con.ViewURI, "json", []byte(`{"items":[]}`))
+
+	var last time.Time
+	for i := range maxSnapshots + 2 {
+		taken, err := StoreSnapshot(db, con, "test", []ConventionEvent{{ID: "1", AvailableQuantity: i}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = taken
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	taken, err := Snapshots(db, con)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(taken) != maxSnapshots || !taken[len(taken)-1].Equal(last) {
+		t.Fatalf("snapshots = %v, want %d ending at %v", taken, maxSnapshots, last)
+	}
+	ez, err := ReadSnapshot(db, con, taken[0])
+	if err != nil || len(ez) != 1 || ez[0].AvailableQuantity != 2 {
+		t.Fatalf("oldest snapshot = %+v, %v", ez, err)
+	}
+
+	parsed, err := ParseSnapshotTime(FormatSnapshotTime(last))
+	if err != nil || !parsed.Equal(last) {
+		t.Fatalf("parsed %v, %v; want %v", parsed, err, last)
+	}
+}
@@ -0,0 +1,95 @@
+package tte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Sentinel errors for the failures callers may want to recover from. Both
+// *ApiError and *StatusError match them with errors.Is.
+var (
+	ErrSessionExpired      = errors.New("tabletop.events session expired")
+	ErrAuthRequired        = errors.New("tabletop.events authentication required")
+	ErrAdminRequired       = errors.New("tabletop.events admin access required")
+	ErrNotFound            = errors.New("tabletop.events resource not found")
+	ErrRateLimited         = errors.New("tabletop.events rate limit exceeded")
+	ErrUpstreamUnavailable = errors.New("tabletop.events is unavailable")
+	// ErrOffline is returned by an offline client for any request it could
+	// not answer from the DB.
+	ErrOffline = errors.New("tabletop.events is not used offline")
+)
+
+// Unreachable reports whether err means tabletop.events could not be
+// reached, or is down, as opposed to refusing the request.
+func Unreachable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var ne net.Error
+	return errors.Is(err, ErrUpstreamUnavailable) || errors.Is(err, ErrOffline) || errors.As(err, &ne)
+}
+
+// tabletop.events api error codes
+const (
+	apiCodeBadSession    = 401
+	apiCodeNotFound      = 404
+	apiCodeRateLimited   = 429
+	apiCodeNoSession     = 441
+	apiCodeAdminRequired = 450
+)
+
+// sentinelForCode maps an api error code or http status to a sentinel error.
+func sentinelForCode(code int) error {
+	switch {
+	case code == apiCodeBadSession, code == http.StatusForbidden:
+		return ErrAuthRequired
+	case code == apiCodeNoSession:
+		return ErrSessionExpired
+	case code == apiCodeAdminRequired:
+		return ErrAdminRequired
+	case code == apiCodeNotFound:
+		return ErrNotFound
+	case code == apiCodeRateLimited:
+		return ErrRateLimited
+	case code >= 500 && code < 600:
+		return ErrUpstreamUnavailable
+	}
+	return nil
+}
+
+const decodeSnippetLength = 120
+
+// DecodeError is returned when a tabletop.events response is not the json
+// we expected, e.g. an html error page or an empty body.
+type DecodeError struct {
+	Snippet string
+	Err     error
+}
+
+func (de *DecodeError) Error() string {
+	if len(de.Snippet) == 0 {
+		return fmt.Sprintf("unable to decode empty response: %s", de.Err)
+	}
+	return fmt.Sprintf("unable to decode response %q: %s", de.Snippet, de.Err)
+}
+
+func (de *DecodeError) Unwrap() error {
+	return de.Err
+}
+
+func newDecodeError(body []byte, err error) *DecodeError {
+	snippet := strings.TrimSpace(string(body))
+	if len(snippet) > decodeSnippetLength {
+		snippet = snippet[:decodeSnippetLength]
+		for !utf8.ValidString(snippet) {
+			snippet = snippet[:len(snippet)-1]
+		}
+		snippet += "..."
+	}
+	return &DecodeError{Snippet: snippet, Err: err}
+}
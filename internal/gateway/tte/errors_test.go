@@ -0,0 +1,50 @@
+package tte
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorsMatchSentinels(t *testing.T) {
+	cases := []struct {
+		err  error
+		want error
+	}{
+		{&ApiError{Code: 441}, ErrSessionExpired},
+		{&ApiError{Code: 401}, ErrAuthRequired},
+		{&ApiError{Code: 450}, ErrAdminRequired},
+		{&ApiError{Code: 404}, ErrNotFound},
+		{&StatusError{StatusCode: http.StatusTooManyRequests}, ErrRateLimited},
+		{&StatusError{StatusCode: http.StatusServiceUnavailable}, ErrUpstreamUnavailable},
+		{&PageError{Page: 3, Err: &ApiError{Code: 441}}, ErrSessionExpired},
+	}
+	for _, tc := range cases {
+		if !errors.Is(tc.err, tc.want) {
+			t.Errorf("errors.Is(%v, %v) = false", tc.err, tc.want)
+		}
+	}
+	if errors.Is(&ApiError{Code: 450}, ErrSessionExpired) {
+		t.Error("admin required matched session expired")
+	}
+}
+
+func TestHTMLResponseIsDecodeError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><body>down for maintenance</body></html>"))
+	}))
+	defer srv.Close()
+
+	s := Session{id: "sess-1", client: NewClient(quietLog, NewMemoryDB(), "test-key", WithBaseURL(srv.URL)), log: quietLog}
+	_, err := s.GetConventionEventTypeContext(context.Background(), "/api/eventtype/rpg")
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("err = %v, want a *DecodeError", err)
+	}
+	if de.Snippet != "<html><body>down for maintenance</body></html>" {
+		t.Fatalf("snippet = %q", de.Snippet)
+	}
+}
@@ -1,29 +1,43 @@
 package tte
 
-import "encoding/json"
+import (
+	"context"
+)
 
 func (s Session) GetConventionEventType(uri string) (cet ConventionEventType, err error) {
+	return s.GetConventionEventTypeContext(context.Background(), uri)
+}
+
+// GetConventionEventTypeContext returns the event type at uri, from the
+// cache while its CachePolicy says it is fresh.
+func (s Session) GetConventionEventTypeContext(ctx context.Context, uri string) (cet ConventionEventType, err error) {
 	var resp ConventionEventTypeResponse
 
+	if age, e := s.client.db.CacheAge(CacheEventType, uri, "json"); e == nil && s.client.cachePolicies.Fresh(CacheEventType, age, Convention{}) {
+		if _, e = readRecord(s.client.db, CacheEventType, uri, &resp); e == nil && resp.Err == nil {
+			return resp.Result, nil
+		}
+	}
+
 	params := map[string]string{
-		"session_id":             s.ID,
 		"_include_relationships": "1",
 	}
 
 	var b []byte
-	b, err = s.client.httpGet(uri, params, nil)
+	b, err = s.get(ctx, uri, params)
 	if err != nil {
 		return cet, err
 	}
-	err = json.Unmarshal(b, &resp)
+	err = decodeResponse(b, &resp)
 	if err != nil {
 		return cet, err
 	}
-	s.client.db.Store("event_type", uri, "json", b)
-
 	if resp.Err != nil {
 		return cet, resp.Err
 	}
+	if e := storeRecord(s.client.db, CacheEventType, uri, s.client.sourceURL(uri), resp); e != nil {
+		s.log.Warn("unable to cache event type", "error", e)
+	}
 	cet = resp.Result
 	return cet, nil
 }
@@ -1,7 +1,7 @@
 package tte
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -10,65 +10,39 @@ import (
 
 func (s Session) GetCachedConventionEvents(con Convention) (cache ConventionEventCache, err error) {
 	c := &ConventionEvents{}
-	var b []byte
-	b, err = s.client.db.Read("events", con.ViewURI, "json")
-	if err != nil {
-		return cache, err
-	}
-	err = json.Unmarshal(b, c)
+	var r Record
+	r, err = readRecord(s.client.db, CacheEvents, con.ViewURI, c)
 	if err != nil {
 		return cache, err
 	}
 	cache.ConventionEvents = c.Items
-	cache.Age, err = s.client.db.CacheAge("events", con.ViewURI, "json")
+	cache.Age, err = recordAge(s.client.db, r, CacheEvents, con.ViewURI)
+	cache.TTL, _ = s.client.cachePolicies.TTL(CacheEvents, time.Now(), con)
+	cache.Fresh = s.client.cachePolicies.Fresh(CacheEvents, cache.Age, con)
 	return cache, err
 }
 
 func (s Session) GetConventionEvents(con Convention) (ez []ConventionEvent, err error) {
-	conID := con.ID
-	var resp ConventionEventsRespose
-	resp, err = s.getConventionEventsByPage(conID, 1)
+	return s.GetConventionEventsContext(context.Background(), con)
+}
+
+// GetConventionEventsContext fetches every event of con, refreshes the
+// events cache and keeps the fetch as a snapshot.
+func (s Session) GetConventionEventsContext(ctx context.Context, con Convention) (ez []ConventionEvent, err error) {
+	uri := fmt.Sprintf("/api/convention/%s/events", con.ID)
+	ez, err = ListAll[ConventionEvent](ctx, s, uri, map[string]string{"_include_relationships": "1"})
 	if err != nil {
 		return ez, err
 	}
-	ez = append(ez, resp.Result.Items...)
 
-	nextPage := resp.Result.Paging.NextPageNumber
-	pageCount := resp.Result.Paging.TotalPages
-	if pageCount == 0 {
-		return ez, err
-	}
-	for i := nextPage; i <= pageCount; i++ {
-		s.log.Debug("getting pages", "current", i, "last", resp.Result.Paging.TotalPages)
-		resp, _ = s.getConventionEventsByPage(conID, int(i))
-		if resp.Err != nil {
-			return ez, fmt.Errorf("[%d]: (%s) %s", resp.Err.Code, resp.Err.Data, resp.Err.Message)
-		}
-		ez = append(ez, resp.Result.Items...)
+	c := &ConventionEvents{Items: ez, Refreshed: time.Now().UTC()}
+	if e := storeRecord(s.client.db, CacheEvents, con.ViewURI, s.client.sourceURL(uri), c); e != nil {
+		s.log.Warn("unable to cache convention events", "error", e)
 	}
-	c := &ConventionEvents{Items: ez}
-	var b []byte
-	if b, err = json.Marshal(c); err == nil {
-		s.client.db.Store("events", con.ViewURI, "json", b)
+	if _, e := StoreSnapshot(s.client.db, con, s.client.sourceURL(uri), ez); e != nil {
+		s.log.Warn("unable to store convention events snapshot", "error", e)
 	}
-	return ez, err
-
-}
-
-func (s Session) getConventionEventsByPage(conID string, page int) (cr ConventionEventsRespose, err error) {
-	params := map[string]string{
-		"session_id":             s.ID,
-		"_page_number":           fmt.Sprintf("%d", page),
-		"_items_per_page":        "100",
-		"_include_relationships": "1",
-	}
-	uri := fmt.Sprintf("/api/convention/%s/events", conID)
-	var b []byte
-	b, err = s.client.httpGet(uri, params, nil)
-
-	cer := ConventionEventsRespose{}
-	err = json.Unmarshal(b, &cer)
-	return cer, err
+	return ez, nil
 }
 
 type FilterableConventionEvents []ConventionEvent
@@ -96,36 +70,52 @@ func (ez FilterableConventionEvents) Filter(predicates ...EventPredicate) (filte
 type ConventionEventCache struct {
 	ConventionEvents []ConventionEvent
 	Age              time.Duration
-}
-
-type ConventionEventsRespose struct {
-	Result ConventionEvents `json:"result"`
-	Err    *ApiError        `json:"error"`
+	// TTL is how long the cache stays fresh under its CachePolicy.
+	TTL   time.Duration
+	Fresh bool
 }
 
 type ConventionEvents struct {
 	Items  []ConventionEvent `json:"items"`
 	Paging Paging            `json:"paging"`
+	// Refreshed is when every event was last fetched, as opposed to only
+	// the updated ones. It is only set in the cache.
+	Refreshed time.Time `json:"refreshed,omitzero"`
 }
 
-// Daytime time
+// Daytime is the display name of a daypart, e.g. "Thursday at  9:00 AM".
+// Use a Timetable to order events; Compare only understands the name.
 type Daytime string
 
 var dayOrder = map[string]int{
 	"Monday":    0,
-	"Tuesday":   2,
-	"Wednesday": 3,
-	"Thursday":  4,
-	"Friday":    5,
-	"Saturday":  6,
-	"Sunday":    7,
+	"Tuesday":   1,
+	"Wednesday": 2,
+	"Thursday":  3,
+	"Friday":    4,
+	"Saturday":  5,
+	"Sunday":    6,
 }
 
+// Split returns the day, the time of day and whether it is before noon. It
+// returns zero values for a daytime it cannot parse.
 func (dt Daytime) Split() (day string, time string, am bool) {
-	parts := strings.Split(strings.ReplaceAll(string(dt), "  ", " "), " ")
+	parts := strings.Fields(string(dt))
+	if len(parts) < 4 {
+		return "", "", false
+	}
 	return parts[0], parts[2], strings.ToLower(parts[3]) == "am"
 }
 
+// parts returns the day and the "3:04 PM" time of day of dt.
+func (dt Daytime) parts() (day string, clock string, ok bool) {
+	parts := strings.Fields(string(dt))
+	if len(parts) < 4 {
+		return "", "", false
+	}
+	return parts[0], parts[2] + " " + strings.ToUpper(parts[3]), true
+}
+
 // Compare return true when dt should come before other
 func (dt Daytime) Compare(other Daytime) bool {
 	aDay, aT, aAM := dt.Split()
@@ -145,9 +135,10 @@ func (dt Daytime) Compare(other Daytime) bool {
 	if aTime[0] != bTime[0] {
 		ai, _ := strconv.Atoi(aTime[0])
 		bi, _ := strconv.Atoi(bTime[0])
-		return ai < bi
+		// 12 o'clock comes before 1
+		return ai%12 < bi%12
 	}
-	if len(aT) > 1 && len(bT) > 1 {
+	if len(aTime) > 1 && len(bTime) > 1 {
 		return aTime[1] < bTime[1]
 	}
 	return dt < other
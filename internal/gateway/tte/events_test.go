@@ -36,3 +36,18 @@ func TestDayTimeCompare(t *testing.T) {
 		t.Errorf("[%s] was less than [%s]", dt4, dt2)
 	}
 }
+
+func TestDayTimeOrdersDaysAndSurvivesShortStrings(t *testing.T) {
+	if !Daytime("Monday at 9:00 AM").Compare("Tuesday at 9:00 AM") {
+		t.Error("Monday sorted after Tuesday")
+	}
+	if !Daytime("Friday at 12:30 PM").Compare("Friday at  1:00 PM") {
+		t.Error("12:30 PM sorted after 1:00 PM")
+	}
+	for _, dt := range []Daytime{"", "Friday", "Friday at"} {
+		if day, clock, am := dt.Split(); day != "" || clock != "" || am {
+			t.Errorf("Split(%q) = %q, %q, %v", dt, day, clock, am)
+		}
+		_ = dt.Compare("Friday at 9:00 AM")
+	}
+}
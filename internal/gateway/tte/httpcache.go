@@ -0,0 +1,209 @@
+package tte
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sync/atomic"
+
+	"github.com/dan-frohlich/tabetopevents/internal/logging"
+)
+
+// CacheHTTP is the id of the responses kept by a CachingTransport.
+const CacheHTTP = "http"
+
+// CacheStatusHeader is set on responses a CachingTransport answered from
+// the DB to how it did so.
+const CacheStatusHeader = "X-Tte-Cache"
+
+const (
+	// CacheRevalidated marks a cached response the server confirmed with
+	// a 304.
+	CacheRevalidated = "revalidated"
+	// CacheOffline marks a cached response served because the client is
+	// offline.
+	CacheOffline = "offline"
+)
+
+// uncachedParams are left out of cache keys: they carry credentials or
+// change with every session without changing the response.
+var uncachedParams = []string{"api_key_id", "session_id"}
+
+// volatileParams differ on nearly every request, e.g. the date_updated
+// filter of an incremental refresh, so requests with them are not cached.
+var volatileParams = []string{updatedSinceParam}
+
+// CachingTransport is an http.RoundTripper that keeps successful GET
+// responses in a DB. It revalidates them with If-None-Match and
+// If-Modified-Since, and serves them as they are when the transport below
+// fails with ErrOffline, i.e. for an offline client. Other errors are
+// returned so the client can retry them and callers never take an old
+// response for a fresh one.
+type CachingTransport struct {
+	base  http.RoundTripper
+	db    DB
+	log   logging.Logger
+	stats *HTTPCacheStats
+}
+
+// HTTPCacheStats counts how a CachingTransport answered requests.
+type HTTPCacheStats struct {
+	// Hits were answered from the DB after a 304.
+	Hits atomic.Int64
+	// Misses went to the server, with or without a cached response.
+	Misses atomic.Int64
+	// Offline were answered from the DB because the client was offline.
+	Offline atomic.Int64
+}
+
+// NewCachingTransport caches the GET responses of base, or of
+// http.DefaultTransport when base is nil, in db.
+func NewCachingTransport(base http.RoundTripper, db DB, log logging.Logger) CachingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return CachingTransport{base: base, db: db, log: log, stats: &HTTPCacheStats{}}
+}
+
+// Stats returns the transport's running counts.
+func (t CachingTransport) Stats() *HTTPCacheStats {
+	return t.stats
+}
+
+type cachedResponse struct {
+	StatusCode   int    `json:"status_code"`
+	ContentType  string `json:"content_type"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Body         []byte `json:"body"`
+}
+
+func (t CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || volatile(req.URL) {
+		return t.base.RoundTrip(req)
+	}
+	kind := httpCacheKind(req.URL)
+	var cached cachedResponse
+	_, err := readRecord(t.db, CacheHTTP, kind, &cached)
+	have := err == nil
+
+	out := req
+	if have && (len(cached.ETag) > 0 || len(cached.LastModified) > 0) {
+		out = req.Clone(req.Context())
+		if len(cached.ETag) > 0 {
+			out.Header.Set("If-None-Match", cached.ETag)
+		}
+		if len(cached.LastModified) > 0 {
+			out.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(out)
+	switch {
+	case err != nil:
+		if !have || !errors.Is(err, ErrOffline) {
+			t.stats.Misses.Add(1)
+			return resp, err
+		}
+		t.stats.Offline.Add(1)
+		t.logResult(req, CacheOffline, "error", err)
+		return cached.response(req, CacheOffline), nil
+	case resp.StatusCode == http.StatusNotModified && have:
+		_ = resp.Body.Close()
+		t.stats.Hits.Add(1)
+		t.logResult(req, CacheRevalidated)
+		return cached.response(req, CacheRevalidated), nil
+	}
+
+	t.stats.Misses.Add(1)
+	if resp.StatusCode != http.StatusOK {
+		t.logResult(req, "miss", "status", resp.StatusCode)
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if !hasApiError(body) {
+		fresh := cachedResponse{
+			StatusCode:   resp.StatusCode,
+			ContentType:  resp.Header.Get("Content-Type"),
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Body:         body,
+		}
+		if e := storeRecord(t.db, CacheHTTP, kind, sanitizedURL(req.URL), fresh); e != nil {
+			t.log.Warn("unable to cache response", "url", sanitizedURL(req.URL), "error", e)
+		}
+	}
+	t.logResult(req, "miss", "status", resp.StatusCode)
+	return resp, nil
+}
+
+func (t CachingTransport) logResult(req *http.Request, result string, args ...any) {
+	args = append([]any{"url", sanitizedURL(req.URL), "result", result,
+		"hits", t.stats.Hits.Load(), "misses", t.stats.Misses.Load(), "offline", t.stats.Offline.Load()}, args...)
+	t.log.Debug("http cache", args...)
+}
+
+func (cr cachedResponse) response(req *http.Request, status string) *http.Response {
+	h := http.Header{}
+	h.Set(CacheStatusHeader, status)
+	if len(cr.ContentType) > 0 {
+		h.Set("Content-Type", cr.ContentType)
+	}
+	if len(cr.ETag) > 0 {
+		h.Set("ETag", cr.ETag)
+	}
+	if len(cr.LastModified) > 0 {
+		h.Set("Last-Modified", cr.LastModified)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", cr.StatusCode, http.StatusText(cr.StatusCode)),
+		StatusCode:    cr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(cr.Body)),
+		ContentLength: int64(len(cr.Body)),
+		Request:       req,
+	}
+}
+
+// httpCacheKind files a response under its path and a checksum of its
+// query, leaving out the uncached params.
+func httpCacheKind(u *url.URL) string {
+	return path.Join(u.Path, checksum([]byte(cacheQuery(u).Encode()))[:16])
+}
+
+func volatile(u *url.URL) bool {
+	q := u.Query()
+	for _, p := range volatileParams {
+		if q.Has(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func cacheQuery(u *url.URL) url.Values {
+	q := u.Query()
+	for _, p := range uncachedParams {
+		q.Del(p)
+	}
+	return q
+}
+
+// sanitizedURL is u without the uncached params, safe to log and store.
+func sanitizedURL(u *url.URL) string {
+	s := *u
+	s.RawQuery = cacheQuery(u).Encode()
+	return s.String()
+}
@@ -0,0 +1,161 @@
+package tte
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCachingTransportRevalidatesAndServesOffline(t *testing.T) {
+	var conditional int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"result":{"id":"rpg"}}`)
+	}))
+	defer srv.Close()
+
+	db := NewMemoryDB()
+	ct := NewCachingTransport(srv.Client().Transport, db, quietLog)
+	get := func(rt http.RoundTripper, session string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/eventtype/rpg?api_key_id=k&session_id="+session, nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip: %v", err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp, string(b)
+	}
+
+	resp, body := get(ct, "s1")
+	if resp.Header.Get(CacheStatusHeader) != "" || !strings.Contains(body, "rpg") {
+		t.Fatalf("first response %v: %s", resp.Header, body)
+	}
+	// a new session still finds the response
+	resp, body = get(ct, "s2")
+	if resp.Header.Get(CacheStatusHeader) != CacheRevalidated || conditional != 1 || !strings.Contains(body, "rpg") {
+		t.Fatalf("revalidated response %v: %s", resp.Header, body)
+	}
+
+	keys, _ := db.List("")
+	for _, k := range keys {
+		b, _ := db.Read(k.ID, k.Kind, k.DataType)
+		if strings.Contains(string(b), "session_id") || strings.Contains(string(b), "api_key_id") {
+			t.Fatalf("credentials leaked into record %s", b)
+		}
+	}
+
+	// a network error is left to the client to retry, even with a cached copy
+	failing := NewCachingTransport(failingTransport{}, db, quietLog)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/eventtype/rpg", nil)
+	if _, err := failing.RoundTrip(req); !errors.Is(err, errOffline) {
+		t.Fatalf("network error err = %v", err)
+	}
+
+	offline := NewCachingTransport(offlineTransport{}, db, quietLog)
+	resp, body = get(offline, "s3")
+	if resp.Header.Get(CacheStatusHeader) != CacheOffline || !strings.Contains(body, "rpg") {
+		t.Fatalf("offline response %v: %s", resp.Header, body)
+	}
+
+	st := ct.Stats()
+	if st.Hits.Load() != 1 || st.Misses.Load() != 1 || offline.Stats().Offline.Load() != 1 {
+		t.Fatalf("stats = %d hits, %d misses, %d offline", st.Hits.Load(), st.Misses.Load(), offline.Stats().Offline.Load())
+	}
+}
+
+func TestCachingTransportSkipsErrorsAndPosts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"error":{"code":441,"message":"session expired"}}`)
+	}))
+	defer srv.Close()
+
+	db := NewMemoryDB()
+	ct := NewCachingTransport(srv.Client().Transport, db, quietLog)
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		req, _ := http.NewRequest(method, srv.URL+"/api/user", nil)
+		resp, err := ct.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		resp.Body.Close()
+	}
+	if keys, _ := db.List(""); len(keys) != 0 {
+		t.Fatalf("cached %v", keys)
+	}
+
+	offline := NewCachingTransport(offlineTransport{}, db, quietLog)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/user", nil)
+	if _, err := offline.RoundTrip(req); !errors.Is(err, ErrOffline) {
+		t.Fatalf("uncached offline request err = %v", err)
+	}
+}
+
+var errOffline = errors.New("network is unreachable")
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errOffline
+}
+
+func TestOfflineClientAnswersFromCache(t *testing.T) {
+	srv := newFakeTTE(t, 5)
+	db := NewMemoryDB()
+	online := NewClient(quietLog, db, "test-key", WithBaseURL(srv.URL), WithHTTPClient(srv.Client()))
+	s, err := online.NewSession("gm", "secret")
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	if _, err = s.GetConventionEventType("/api/eventtype/rpg"); err != nil {
+		t.Fatalf("GetConventionEventType: %v", err)
+	}
+	_ = db.Delete(CacheEventType, "/api/eventtype/rpg", "json")
+
+	c := NewOfflineClient(quietLog, db, WithBaseURL(srv.URL), WithHTTPClient(srv.Client()))
+	if !c.Offline() {
+		t.Fatal("offline client is online")
+	}
+	if _, err = c.NewSession("gm", "secret"); !errors.Is(err, ErrOffline) || !Unreachable(err) {
+		t.Fatalf("offline NewSession err = %v", err)
+	}
+	off := c.OfflineSession()
+	cet, err := off.GetConventionEventType("/api/eventtype/rpg")
+	if err != nil || cet.Name != "RPG" {
+		t.Fatalf("offline event type = %+v, %v", cet, err)
+	}
+	if _, err = off.GetConventionEvents(Convention{ID: "con-1"}); !errors.Is(err, ErrOffline) {
+		t.Fatalf("offline GetConventionEvents err = %v", err)
+	}
+}
+
+func TestCachingTransportSkipsVolatileRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"result":{"items":[]}}`)
+	}))
+	defer srv.Close()
+
+	db := NewMemoryDB()
+	ct := NewCachingTransport(srv.Client().Transport, db, quietLog)
+	for _, since := range []string{"2025-07-01", "2025-07-02"} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/convention/1/events?"+updatedSinceParam+"=%3E%3D"+since, nil)
+		resp, err := ct.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if keys, _ := db.List(""); len(keys) != 0 {
+		t.Fatalf("cached %v", keys)
+	}
+}
@@ -0,0 +1,365 @@
+package tte
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ItineraryConstraints limit what BuildItinerary may schedule. Zero values
+// impose no limit.
+type ItineraryConstraints struct {
+	// DayStart and DayEnd bound each day, as the time since midnight.
+	DayStart time.Duration
+	DayEnd   time.Duration
+	// MinBreak is the least time between two scheduled events.
+	MinBreak time.Duration
+	// MaxPerDay caps the time scheduled on any one day.
+	MaxPerDay time.Duration
+}
+
+// Itinerary is a personal schedule built from liked events.
+type Itinerary struct {
+	// Events are the scheduled events, by start time.
+	Events   []ConventionEvent
+	Priority int
+	Dropped  []DroppedEvent
+	// Complete is set when the search finished, so no itinerary has a
+	// higher priority. Otherwise the best one found in time is returned.
+	Complete bool
+}
+
+// DroppedEvent is a liked event left out of an itinerary, and why.
+type DroppedEvent struct {
+	Event  ConventionEvent
+	Reason string
+}
+
+// maxItineraryNodes bounds the search; past it the best itinerary found so
+// far is returned.
+const maxItineraryNodes = 500_000
+
+// BuildItinerary picks the liked events of ez that fit together under c
+// with the highest total priority. Exempt events may overlap others but
+// still count toward MaxPerDay.
+func BuildItinerary(ez []ConventionEvent, lz Likes, tt Timetable, c ItineraryConstraints) (it Itinerary) {
+	var candidates []ConventionEvent
+	for _, e := range ez {
+		if !lz.Has(e.ViewURI) {
+			continue
+		}
+		if reason := c.excludes(e, tt); len(reason) > 0 {
+			it.Dropped = append(it.Dropped, DroppedEvent{Event: e, Reason: reason})
+			continue
+		}
+		candidates = append(candidates, e)
+	}
+	tt.Sort(candidates)
+
+	s := newItinerarySearch(candidates, lz, tt, c)
+	s.search(0, nil, 0, time.Time{})
+	it.Complete = !s.truncated
+
+	chosen := make(map[int]struct{}, len(s.best))
+	for _, i := range s.best {
+		chosen[i] = struct{}{}
+		it.Events = append(it.Events, candidates[i])
+		it.Priority += s.candidates[i].like.Priority
+	}
+	for i, e := range candidates {
+		if _, ok := chosen[i]; !ok {
+			it.Dropped = append(it.Dropped, DroppedEvent{Event: e, Reason: s.explain(i)})
+		}
+	}
+	tt.Sort(it.Events)
+	return it
+}
+
+// excludes says why e can never be scheduled under c, if it cannot.
+func (c ItineraryConstraints) excludes(e ConventionEvent, tt Timetable) string {
+	t, ok := tt.Get(e)
+	switch {
+	case e.IsCancelled != 0:
+		return "it was cancelled"
+	case !ok:
+		return "its start time is unknown"
+	case c.DayStart > 0 && sinceMidnight(t.Start) < c.DayStart:
+		return fmt.Sprintf("it starts before %s", clock(c.DayStart))
+	case c.DayEnd > 0 && sinceMidnight(t.Start)+t.End.Sub(t.Start) > c.DayEnd:
+		return fmt.Sprintf("it ends after %s", clock(c.DayEnd))
+	case c.MaxPerDay > 0 && t.End.Sub(t.Start) > c.MaxPerDay:
+		return fmt.Sprintf("it is longer than the %s allowed per day", c.MaxPerDay)
+	}
+	return ""
+}
+
+// itineraryCandidate is a liked event that may be scheduled, with what the
+// search needs of it looked up once.
+type itineraryCandidate struct {
+	event  ConventionEvent
+	like   Like
+	time   EventTime
+	length time.Duration
+	// day numbers the days candidates start on, from 0
+	day int
+	// group numbers the like's group, or is -1 without one
+	group  int
+	exempt bool
+}
+
+// free reports whether c never keeps an event from being scheduled beside
+// it, as it may overlap or takes no time.
+func (c itineraryCandidate) free() bool {
+	return c.exempt || c.length <= 0
+}
+
+// itinerarySearch is a branch and bound search over the candidates in start
+// order. Each node is bounded per day by the best priority of the events
+// left that do not overlap, capped by how much of them fits in the time
+// left that day.
+type itinerarySearch struct {
+	candidates []itineraryCandidate
+	c          ItineraryConstraints
+	// days[d] is the first candidate of day d and days[d+1] the one after
+	// its last
+	days []int
+	// spaced[i] is the best priority of events from i to the end of its
+	// day that are not free and do not overlap; freed[i] that of the free
+	// ones
+	spaced []int
+	freed  []int
+	// byDensity holds the candidates of each day by priority per hour,
+	// highest first
+	byDensity [][]int
+	// later[d] bounds the priority of the days after d
+	later []int
+
+	dayTotals []time.Duration
+	groupUsed []bool
+
+	best      []int
+	bestTotal int
+	nodes     int
+	truncated bool
+}
+
+func newItinerarySearch(ez []ConventionEvent, lz Likes, tt Timetable, c ItineraryConstraints) *itinerarySearch {
+	likes := make(map[string]Like, len(lz))
+	for _, l := range lz {
+		likes[l.ViewURI] = l
+	}
+	s := &itinerarySearch{c: c}
+	groups := map[string]int{}
+	for i, e := range ez {
+		ec := itineraryCandidate{event: e, like: likes[e.ViewURI], time: tt[e.ID], group: -1, exempt: e.Exempt()}
+		ec.length = ec.time.End.Sub(ec.time.Start)
+		if i == 0 || dayOf(ec.time.Start) != dayOf(s.candidates[i-1].time.Start) {
+			s.days = append(s.days, i)
+		}
+		ec.day = len(s.days) - 1
+		if len(ec.like.Group) > 0 {
+			g, ok := groups[ec.like.Group]
+			if !ok {
+				g = len(groups)
+				groups[ec.like.Group] = g
+			}
+			ec.group = g
+		}
+		s.candidates = append(s.candidates, ec)
+	}
+	s.days = append(s.days, len(ez))
+	s.dayTotals = make([]time.Duration, len(s.days)-1)
+	s.groupUsed = make([]bool, len(groups))
+
+	s.spaced = make([]int, len(ez))
+	s.freed = make([]int, len(ez))
+	s.byDensity = make([][]int, len(s.days)-1)
+	for d := range s.byDensity {
+		first, end := s.days[d], s.days[d+1]
+		for i := end - 1; i >= first; i-- {
+			ec := s.candidates[i]
+			s.spaced[i], s.freed[i] = s.spacedFrom(i+1, end), s.freedFrom(i+1, end)
+			if ec.free() {
+				s.freed[i] += ec.like.Priority
+				continue
+			}
+			s.spaced[i] = max(s.spaced[i], ec.like.Priority+s.spacedFrom(s.firstAfter(i+1, end, ec.time.End.Add(c.MinBreak)), end))
+		}
+		for i := first; i < end; i++ {
+			s.byDensity[d] = append(s.byDensity[d], i)
+		}
+		sort.SliceStable(s.byDensity[d], func(a, b int) bool {
+			ca, cb := s.candidates[s.byDensity[d][a]], s.candidates[s.byDensity[d][b]]
+			if ca.length <= 0 || cb.length <= 0 {
+				return ca.length <= 0 && cb.length > 0
+			}
+			return int64(ca.like.Priority)*int64(cb.length) > int64(cb.like.Priority)*int64(ca.length)
+		})
+	}
+	s.later = make([]int, len(s.days))
+	for d := len(s.days) - 2; d > 0; d-- {
+		s.later[d-1] = s.later[d] + s.dayBound(d, s.days[d], s.days[d])
+	}
+	return s
+}
+
+func (s *itinerarySearch) spacedFrom(i int, end int) int {
+	if i >= end {
+		return 0
+	}
+	return s.spaced[i]
+}
+
+func (s *itinerarySearch) freedFrom(i int, end int) int {
+	if i >= end {
+		return 0
+	}
+	return s.freed[i]
+}
+
+// firstAfter is the first candidate from i up to end that starts at or
+// after t.
+func (s *itinerarySearch) firstAfter(i int, end int, t time.Time) int {
+	return i + sort.Search(end-i, func(j int) bool {
+		return !s.candidates[i+j].time.Start.Before(t)
+	})
+}
+
+// dayBound bounds the priority the candidates from i on day d can add,
+// given that those not free before k overlap a chosen event.
+func (s *itinerarySearch) dayBound(d int, i int, k int) int {
+	end := s.days[d+1]
+	bound := s.freedFrom(i, end) + s.spacedFrom(k, end)
+	if s.c.MaxPerDay <= 0 {
+		return bound
+	}
+	left, fits := s.c.MaxPerDay-s.dayTotals[d], 0.0
+	for _, j := range s.byDensity[d] {
+		ec := s.candidates[j]
+		if j < i || (j < k && !ec.free()) || (ec.group >= 0 && s.groupUsed[ec.group]) {
+			continue
+		}
+		if ec.length > left {
+			fits += float64(ec.like.Priority) * float64(left) / float64(ec.length)
+			break
+		}
+		fits += float64(ec.like.Priority)
+		left -= max(ec.length, 0)
+	}
+	return min(bound, int(math.Ceil(fits)))
+}
+
+// search decides on candidates from i on, given the chosen ones so far,
+// their total priority and when the last one that may not overlap others
+// ends, break included.
+func (s *itinerarySearch) search(i int, chosen []int, total int, busy time.Time) {
+	s.nodes++
+	if total > s.bestTotal || s.best == nil {
+		s.best, s.bestTotal = append([]int(nil), chosen...), total
+	}
+	if i == len(s.candidates) {
+		return
+	}
+	if s.nodes > maxItineraryNodes {
+		s.truncated = true
+		return
+	}
+	ec := s.candidates[i]
+	if total+s.dayBound(ec.day, i, s.firstAfter(i, s.days[ec.day+1], busy))+s.later[ec.day] <= s.bestTotal {
+		return
+	}
+	if s.fits(i, chosen) {
+		s.dayTotals[ec.day] += ec.length
+		if ec.group >= 0 {
+			s.groupUsed[ec.group] = true
+		}
+		next := busy
+		if end := ec.time.End.Add(s.c.MinBreak); !ec.exempt && end.After(busy) {
+			next = end
+		}
+		s.search(i+1, append(chosen, i), total+ec.like.Priority, next)
+		s.dayTotals[ec.day] -= ec.length
+		if ec.group >= 0 {
+			s.groupUsed[ec.group] = false
+		}
+	}
+	s.search(i+1, chosen, total, busy)
+}
+
+// fits reports whether candidate i can join the chosen ones.
+func (s *itinerarySearch) fits(i int, chosen []int) bool {
+	ec := s.candidates[i]
+	if (ec.group >= 0 && s.groupUsed[ec.group]) || (s.c.MaxPerDay > 0 && s.dayTotals[ec.day]+ec.length > s.c.MaxPerDay) {
+		return false
+	}
+	if ec.exempt {
+		return true
+	}
+	for _, j := range chosen {
+		if overlaps, tooClose := s.clash(ec, s.candidates[j]); overlaps || tooClose {
+			return false
+		}
+	}
+	return true
+}
+
+// clash reports whether a and b overlap, or leave less than MinBreak
+// between them. Exempt events never clash.
+func (s *itinerarySearch) clash(a itineraryCandidate, b itineraryCandidate) (overlaps bool, tooClose bool) {
+	if a.exempt || b.exempt {
+		return false, false
+	}
+	if a.time.Overlaps(b.time) {
+		return true, false
+	}
+	brk := s.c.MinBreak
+	return false, brk > 0 && (a.time.Overlaps(EventTime{Start: b.time.Start, End: b.time.End.Add(brk)}) || b.time.Overlaps(EventTime{Start: a.time.Start, End: a.time.End.Add(brk)}))
+}
+
+// blockers says why candidate i cannot join the chosen ones, if it cannot.
+func (s *itinerarySearch) blockers(i int, chosen []int) (reasons []string) {
+	ec := s.candidates[i]
+	day := ec.length
+	for _, j := range chosen {
+		o := s.candidates[j]
+		if ec.group >= 0 && ec.group == o.group {
+			reasons = append(reasons, fmt.Sprintf("its alternative #%d %s is scheduled instead", o.event.EventNumber, o.event.Name))
+			continue
+		}
+		if o.day == ec.day {
+			day += o.length
+		}
+		switch overlaps, tooClose := s.clash(ec, o); {
+		case overlaps:
+			reasons = append(reasons, fmt.Sprintf("it overlaps #%d %s (priority %d)", o.event.EventNumber, o.event.Name, o.like.Priority))
+		case tooClose:
+			reasons = append(reasons, fmt.Sprintf("it leaves less than a %s break around #%d %s", s.c.MinBreak, o.event.EventNumber, o.event.Name))
+		}
+	}
+	if s.c.MaxPerDay > 0 && day > s.c.MaxPerDay {
+		reasons = append(reasons, fmt.Sprintf("it would make %s more than %s of events", ec.time.Start.Format("Monday"), s.c.MaxPerDay))
+	}
+	return reasons
+}
+
+// explain says why candidate i was left out of the best itinerary.
+func (s *itinerarySearch) explain(i int) string {
+	if reasons := s.blockers(i, s.best); len(reasons) > 0 {
+		return reasons[0]
+	}
+	return "the search ran out of time before fitting it in"
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	y, m, d := t.Date()
+	return t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+}
+
+func dayOf(t time.Time) string {
+	return t.Format(time.DateOnly)
+}
+
+func clock(d time.Duration) string {
+	return time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC).Add(d).Format("3:04 PM")
+}
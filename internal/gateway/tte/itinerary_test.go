@@ -0,0 +1,131 @@
+package tte
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildItineraryMaximizesPriority(t *testing.T) {
+	at := func(d, h int) time.Time { return time.Date(2025, 8, d, h, 0, 0, 0, time.UTC) }
+	tt := Timetable{
+		"long":    {Start: at(1, 9), End: at(1, 13)},
+		"short1":  {Start: at(1, 9), End: at(1, 11)},
+		"short2":  {Start: at(1, 11).Add(30 * time.Minute), End: at(1, 13).Add(30 * time.Minute)},
+		"fri-pm":  {Start: at(1, 14), End: at(1, 16)},
+		"sat-am":  {Start: at(2, 10), End: at(2, 12)},
+		"late":    {Start: at(1, 22), End: at(1, 24)},
+		"early":   {Start: at(2, 7), End: at(2, 8)},
+		"open":    {Start: at(1, 9), End: at(1, 18)},
+		"dropped": {Start: at(2, 12), End: at(2, 13)},
+	}
+	ez := []ConventionEvent{
+		{ID: "long", ViewURI: "/e/long", Name: "Long", EventNumber: 1},
+		{ID: "short1", ViewURI: "/e/short1", Name: "Short 1", EventNumber: 2},
+		{ID: "short2", ViewURI: "/e/short2", Name: "Short 2", EventNumber: 3},
+		{ID: "fri-pm", ViewURI: "/e/catan-fri", Name: "Catan", EventNumber: 4},
+		{ID: "sat-am", ViewURI: "/e/catan-sat", Name: "Catan", EventNumber: 5},
+		{ID: "late", ViewURI: "/e/late", Name: "Late", EventNumber: 6},
+		{ID: "early", ViewURI: "/e/early", Name: "Early", EventNumber: 7},
+		{ID: "open", ViewURI: "/e/open", Name: "Open Gaming", EventNumber: 8, AllowScheduleConflicts: 1},
+		{ID: "cancelled", ViewURI: "/e/cancelled", Name: "Cancelled", EventNumber: 9, IsCancelled: 1},
+		{ID: "dropped", ViewURI: "/e/dropped", Name: "Right After", EventNumber: 10},
+		{ID: "not-liked", ViewURI: "/e/not-liked", Name: "Not Liked"},
+	}
+	lz, _ := Likes(nil).Sync([]string{"/e/long", "/e/short1", "/e/short2", "/e/catan-fri", "/e/catan-sat",
+		"/e/late", "/e/early", "/e/open", "/e/cancelled", "/e/dropped"})
+	lz.Update(Like{ViewURI: "/e/long", Priority: 5})
+	lz.Update(Like{ViewURI: "/e/catan-fri", Priority: 4, Group: "catan"})
+	lz.Update(Like{ViewURI: "/e/catan-sat", Priority: 4, Group: "catan"})
+	lz.Update(Like{ViewURI: "/e/open", Priority: 1})
+	lz.Update(Like{ViewURI: "/e/dropped", Priority: 9})
+
+	it := BuildItinerary(ez, lz, tt, ItineraryConstraints{
+		DayStart: 8 * time.Hour,
+		DayEnd:   23 * time.Hour,
+		MinBreak: 30 * time.Minute,
+	})
+
+	var names []string
+	for _, e := range it.Events {
+		names = append(names, e.Name)
+	}
+	// the two shorts (3+3) beat long (5); open gaming overlaps freely and
+	// sorts before Short 1 at 9:00; Right After (5) leaves no break after
+	// Saturday's Catan, so Friday's is the one scheduled
+	if got, want := strings.Join(names, ","), "Open Gaming,Short 1,Short 2,Catan,Right After"; got != want || it.Priority != 16 {
+		t.Errorf("itinerary = %s (priority %d), want %s (priority 16)", got, it.Priority, want)
+	}
+	if it.Events[3].ID != "fri-pm" {
+		t.Errorf("scheduled catan = %s, want fri-pm", it.Events[3].ID)
+	}
+
+	var dropped []string
+	for _, d := range it.Dropped {
+		dropped = append(dropped, d.Event.ID+": "+d.Reason)
+	}
+	want := []string{
+		"late: it ends after 11:00 PM",
+		"early: it starts before 8:00 AM",
+		"cancelled: it was cancelled",
+		"long: it overlaps #2 Short 1 (priority 3)",
+		"sat-am: its alternative #4 Catan is scheduled instead",
+	}
+	if got := strings.Join(dropped, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("dropped:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestBuildItineraryCapsHoursPerDay(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2025, 8, 1, h, 0, 0, 0, time.UTC) }
+	tt := Timetable{"a": {Start: at(9), End: at(12)}, "b": {Start: at(13), End: at(16)}, "c": {Start: at(17), End: at(19)}}
+	ez := []ConventionEvent{{ID: "a", ViewURI: "a"}, {ID: "b", ViewURI: "b"}, {ID: "c", ViewURI: "c", Name: "C"}}
+	lz, _ := Likes(nil).Sync([]string{"a", "b", "c"})
+	lz.Update(Like{ViewURI: "c", Priority: 1})
+
+	it := BuildItinerary(ez, lz, tt, ItineraryConstraints{MaxPerDay: 6 * time.Hour})
+	if len(it.Events) != 2 || len(it.Dropped) != 1 || it.Dropped[0].Event.ID != "c" || !strings.Contains(it.Dropped[0].Reason, "more than 6h") {
+		t.Fatalf("itinerary = %+v", it)
+	}
+}
+
+func TestBuildItineraryScalesToManyOverlappingLikes(t *testing.T) {
+	tt := Timetable{}
+	var ez []ConventionEvent
+	var uris []string
+	for i := range 90 {
+		id := strconv.Itoa(i)
+		start := time.Date(2025, 8, 1+i%3, 8+(i*7)%12, 30*(i%2), 0, 0, time.UTC)
+		tt[id] = EventTime{Start: start, End: start.Add(time.Duration(1+i%4) * time.Hour)}
+		ez = append(ez, ConventionEvent{ID: id, ViewURI: "/e/" + id, Name: id})
+		uris = append(uris, "/e/"+id)
+	}
+	lz, _ := Likes(nil).Sync(uris)
+	for i, uri := range uris {
+		l := Like{ViewURI: uri, Priority: 1 + (i*3)%5}
+		if i%5 == 0 {
+			l.Group = "g" + strconv.Itoa(i%4)
+		}
+		lz.Update(l)
+	}
+
+	begin := time.Now()
+	it := BuildItinerary(ez, lz, tt, ItineraryConstraints{DayStart: 8 * time.Hour, MinBreak: 15 * time.Minute, MaxPerDay: 8 * time.Hour})
+	if took := time.Since(begin); !it.Complete || took > 5*time.Second {
+		t.Fatalf("search complete = %v after %s", it.Complete, took)
+	}
+	perDay := map[string]time.Duration{}
+	for _, e := range it.Events {
+		et := tt[e.ID]
+		perDay[dayOf(et.Start)] += et.End.Sub(et.Start)
+	}
+	for day, d := range perDay {
+		if d > 8*time.Hour {
+			t.Errorf("%s has %s of events", day, d)
+		}
+	}
+	if len(it.Events)+len(it.Dropped) != len(ez) || it.Priority == 0 {
+		t.Fatalf("itinerary = %d events (priority %d), %d dropped", len(it.Events), it.Priority, len(it.Dropped))
+	}
+}
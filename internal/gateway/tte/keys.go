@@ -0,0 +1,80 @@
+package tte
+
+import (
+	"fmt"
+	"strings"
+)
+
+// encodeKeyPart maps an arbitrary id, kind or data type to a string that is
+// safe as a single file name on every platform: lower case letters, digits,
+// '-' and '_' are kept, '.' is kept unless it leads, and every other byte,
+// including '/' and upper case letters (for case-insensitive file systems),
+// becomes %XX. The empty string is a lone %. Distinct inputs never share
+// an encoding.
+func encodeKeyPart(s string) string {
+	if len(s) == 0 {
+		return emptyKeyPart
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_':
+			b.WriteByte(c)
+		case c == '.' && i > 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// encodeDataType is encodeKeyPart with every '.' escaped, so the last '.'
+// of an encoded file name always separates the id from the data type.
+func encodeDataType(s string) string {
+	return strings.ReplaceAll(encodeKeyPart(s), ".", "%2E")
+}
+
+const emptyKeyPart = "%"
+
+func decodeKeyPart(s string) (string, error) {
+	if s == emptyKeyPart {
+		return "", nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", fmt.Errorf("truncated escape in key %q", s)
+		}
+		var c byte
+		if _, err := fmt.Sscanf(s[i+1:i+3], "%02X", &c); err != nil {
+			return "", fmt.Errorf("bad escape in key %q: %w", s, err)
+		}
+		b.WriteByte(c)
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// encodeFileName is the file name of a record within its kind's directory.
+func encodeFileName(id string, dataType string) string {
+	return encodeKeyPart(id) + "." + encodeDataType(dataType)
+}
+
+// decodeFileName reverses encodeFileName.
+func decodeFileName(name string) (id string, dataType string, err error) {
+	i := strings.LastIndex(name, ".")
+	if i <= 0 {
+		return "", "", fmt.Errorf("%q is not a record file name", name)
+	}
+	if id, err = decodeKeyPart(name[:i]); err != nil {
+		return "", "", err
+	}
+	dataType, err = decodeKeyPart(name[i+1:])
+	return id, dataType, err
+}
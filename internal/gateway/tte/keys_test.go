@@ -0,0 +1,43 @@
+package tte
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyEncodingIsSafeAndReversible(t *testing.T) {
+	parts := []string{
+		"",
+		"events",
+		"/convention/gen-con-2025",
+		"/api/eventtype/8D5F3E9A-1C2B",
+		"../../etc/passwd",
+		"..",
+		".hidden",
+		"a%2Fb",
+		"Gen-Con",
+		"gen-con",
+		"tab\tand space",
+		"ünïcode",
+	}
+	seen := map[string]string{}
+	for _, p := range parts {
+		enc := encodeKeyPart(p)
+		if len(enc) == 0 || enc != filepath.Base(enc) || enc == "." || enc == ".." || enc[0] == '.' {
+			t.Errorf("encodeKeyPart(%q) = %q is not a plain file name", p, enc)
+		}
+		if other, ok := seen[enc]; ok {
+			t.Errorf("%q and %q both encode to %q", p, other, enc)
+		}
+		seen[enc] = p
+		dec, err := decodeKeyPart(enc)
+		if err != nil || dec != p {
+			t.Errorf("decodeKeyPart(%q) = %q, %v; want %q", enc, dec, err, p)
+		}
+	}
+
+	id, dataType, err := decodeFileName(encodeFileName("v1.2", "tar.gz"))
+	if err != nil || id != "v1.2" || dataType != "tar.gz" {
+		t.Errorf("decodeFileName = %q, %q, %v", id, dataType, err)
+	}
+}
@@ -0,0 +1,127 @@
+package tte
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// CacheLikes is the id of a profile's liked events of a convention.
+const CacheLikes = "liked"
+
+// Priorities range from MinPriority to MaxPriority; an event is liked at
+// DefaultPriority unless given another.
+const (
+	MinPriority     = 1
+	DefaultPriority = 3
+	MaxPriority     = 5
+)
+
+// Like is a liked event.
+type Like struct {
+	ViewURI  string `json:"view_uri"`
+	Priority int    `json:"priority"`
+	// Group names interchangeable events, e.g. the sessions of one game. An
+	// itinerary holds at most one event of a group.
+	Group string `json:"group,omitempty"`
+}
+
+// Likes are ordered by view uri.
+type Likes []Like
+
+// ReadLikes returns the liked events of con. Likes kept as the plain list
+// of view uris written before likes had priorities are read at
+// DefaultPriority and rewritten. Likes that cannot be decoded are not
+// dropped like a cache would be: they are first copied to the key
+// <view_uri>/unreadable of CacheLikes and then reported with
+// ErrIncompatibleRecord. When the copy fails they are left in place and a
+// plain error is returned.
+func ReadLikes(db DB, con Convention) (lz Likes, err error) {
+	var raw []byte
+	if raw, err = db.Read(CacheLikes, con.ViewURI, "json"); err == nil {
+		if _, err = unmarshalRecord(db, CacheLikes, con.ViewURI, raw, &lz); err == nil {
+			return lz, nil
+		}
+		backup := path.Join(con.ViewURI, "unreadable")
+		if e := db.Store(CacheLikes, backup, "json", raw); e != nil {
+			return nil, fmt.Errorf("unable to set aside the unreadable liked events of %s (%v): %v", con.ViewURI, err, e)
+		}
+		db.Delete(CacheLikes, con.ViewURI, "json")
+		return nil, fmt.Errorf("%w: liked events of %s were set aside as %s: %v", ErrIncompatibleRecord, con.ViewURI, newKey(CacheLikes, backup, "json"), err)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return lz, err
+	}
+	var b []byte
+	if b, err = db.Read(CacheLikes, con.ViewURI, "txt"); err != nil {
+		return nil, err
+	}
+	lz, _ = Likes(nil).Sync(strings.Split(string(b), "\n"))
+	if err = StoreLikes(db, con, lz); err != nil {
+		return lz, err
+	}
+	return lz, db.Delete(CacheLikes, con.ViewURI, "txt")
+}
+
+// StoreLikes replaces the liked events of con.
+func StoreLikes(db DB, con Convention, lz Likes) error {
+	return storeRecord(db, CacheLikes, con.ViewURI, "", lz)
+}
+
+// Get returns the like of the event at uri.
+func (lz Likes) Get(uri string) (l Like, ok bool) {
+	for _, l = range lz {
+		if l.ViewURI == uri {
+			return l, true
+		}
+	}
+	return Like{}, false
+}
+
+// Has reports whether the event at uri is liked.
+func (lz Likes) Has(uri string) bool {
+	_, ok := lz.Get(uri)
+	return ok
+}
+
+// URIs returns the view uris of the liked events.
+func (lz Likes) URIs() (uris []string) {
+	for _, l := range lz {
+		uris = append(uris, l.ViewURI)
+	}
+	return uris
+}
+
+// Sync makes the liked events those at uris, keeping the priority and group
+// of those already liked. added lists the uris that were not.
+func (lz Likes) Sync(uris []string) (synced Likes, added []string) {
+	seen := make(map[string]struct{}, len(uris))
+	for _, uri := range uris {
+		uri = strings.TrimSpace(uri)
+		if _, dup := seen[uri]; dup || len(uri) == 0 {
+			continue
+		}
+		seen[uri] = struct{}{}
+		l, ok := lz.Get(uri)
+		if !ok {
+			l = Like{ViewURI: uri, Priority: DefaultPriority}
+			added = append(added, uri)
+		}
+		synced = append(synced, l)
+	}
+	sort.Slice(synced, func(i, j int) bool { return synced[i].ViewURI < synced[j].ViewURI })
+	return synced, added
+}
+
+// Update replaces the like of l.ViewURI with l, clamping its priority.
+func (lz Likes) Update(l Like) {
+	l.Priority = max(MinPriority, min(MaxPriority, l.Priority))
+	for i := range lz {
+		if lz[i].ViewURI == l.ViewURI {
+			lz[i] = l
+		}
+	}
+}
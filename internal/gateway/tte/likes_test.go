@@ -0,0 +1,63 @@
+package tte
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestReadLikesUpgradesPlainList(t *testing.T) {
+	db := NewMemoryDB()
+	con := Convention{ViewURI: "/convention/test-con"}
+	_ = db.Store(CacheLikes, con.ViewURI, "txt", []byte("/e/b\n/e/a\n\n/e/b"))
+
+	lz, err := ReadLikes(db, con)
+	if err != nil || len(lz) != 2 || lz[0].ViewURI != "/e/a" || lz[0].Priority != DefaultPriority {
+		t.Fatalf("likes = %+v, %v", lz, err)
+	}
+	lz.Update(Like{ViewURI: "/e/a", Priority: 10, Group: "g"})
+	if err = StoreLikes(db, con, lz); err != nil {
+		t.Fatal(err)
+	}
+	if lz, err = ReadLikes(db, con); err != nil || lz[0].Priority != MaxPriority || lz[0].Group != "g" {
+		t.Fatalf("stored likes = %+v, %v", lz, err)
+	}
+	if _, err = db.Read(CacheLikes, con.ViewURI, "txt"); err == nil {
+		t.Fatal("plain list was kept")
+	}
+}
+
+func TestReadLikesSetsAsideUnreadableLikes(t *testing.T) {
+	db := NewMemoryDB()
+	con := Convention{ViewURI: "/convention/test-con"}
+	_ = db.Store(CacheLikes, con.ViewURI, "json", []byte(`{"schema":1,"data":"not likes"}`))
+
+	if _, err := ReadLikes(db, con); !errors.Is(err, ErrIncompatibleRecord) || errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("err = %v", err)
+	}
+	if b, err := db.Read(CacheLikes, con.ViewURI+"/unreadable", "json"); err != nil || !strings.Contains(string(b), "not likes") {
+		t.Fatalf("backup = %s, %v", b, err)
+	}
+}
+
+// readOnlyDB fails every Store, e.g. on a full disk.
+type readOnlyDB struct{ DB }
+
+func (readOnlyDB) Store(string, string, string, []byte) error {
+	return fs.ErrPermission
+}
+
+func TestReadLikesKeepsUnreadableLikesItCannotSetAside(t *testing.T) {
+	db := NewMemoryDB()
+	con := Convention{ViewURI: "/convention/test-con"}
+	_ = db.Store(CacheLikes, con.ViewURI, "json", []byte(`{"schema":1,"data":"not likes"}`))
+
+	_, err := ReadLikes(readOnlyDB{db}, con)
+	if err == nil || errors.Is(err, ErrIncompatibleRecord) || errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("err = %v, want a plain error", err)
+	}
+	if b, err := db.Read(CacheLikes, con.ViewURI, "json"); err != nil || !strings.Contains(string(b), "not likes") {
+		t.Fatalf("likes = %s, %v", b, err)
+	}
+}
@@ -0,0 +1,93 @@
+package tte
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"maps"
+)
+
+const listItemsPerPage = 100
+
+// ListResponse is the envelope tabletop.events wraps around every list
+// endpoint.
+type ListResponse[T any] struct {
+	Result struct {
+		Items  []T     `json:"items"`
+		Paging *Paging `json:"paging"`
+	} `json:"result"`
+	Err *ApiError `json:"error"`
+}
+
+// getListPage fetches a single page of the list at uri.
+func getListPage[T any](ctx context.Context, s Session, uri string, params map[string]string, page int) (lr ListResponse[T], err error) {
+	pageParams := map[string]string{
+		"_page_number":    fmt.Sprintf("%d", page),
+		"_items_per_page": fmt.Sprintf("%d", listItemsPerPage),
+	}
+	maps.Copy(pageParams, params)
+
+	var b []byte
+	b, err = s.get(ctx, uri, pageParams)
+	if err != nil {
+		return lr, err
+	}
+	err = decodeResponse(b, &lr)
+	if err != nil {
+		return lr, err
+	}
+	if lr.Err != nil {
+		return lr, lr.Err
+	}
+	return lr, nil
+}
+
+// List streams every item of the tabletop.events list at uri, one page at a
+// time. params are added to every page request. Breaking out of the range
+// loop stops paging; an error is yielded once and ends the sequence.
+//
+//	for ev, err := range tte.List[tte.ConventionEvent](ctx, s, "/api/convention/"+con.ID+"/events", nil) {
+func List[T any](ctx context.Context, s Session, uri string, params map[string]string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for page := 1; ; page++ {
+			lr, err := getListPage[T](ctx, s, uri, params, page)
+			if err != nil {
+				var zero T
+				yield(zero, &PageError{Page: page, Err: err})
+				return
+			}
+			for _, item := range lr.Result.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if lr.Result.Paging == nil || int64(page) >= lr.Result.Paging.TotalPages {
+				return
+			}
+		}
+	}
+}
+
+// ListAll collects every item of the list at uri, fetching the pages after
+// the first concurrently.
+func ListAll[T any](ctx context.Context, s Session, uri string, params map[string]string) (items []T, err error) {
+	var lr ListResponse[T]
+	lr, err = getListPage[T](ctx, s, uri, params, 1)
+	if err != nil {
+		return items, err
+	}
+	items = append(items, lr.Result.Items...)
+	if lr.Result.Paging == nil {
+		return items, nil
+	}
+
+	var rest []T
+	rest, err = fetchRemainingPages(ctx, s.client.pageWorkers, int(lr.Result.Paging.TotalPages), func(ctx context.Context, page int) ([]T, error) {
+		lr, err := getListPage[T](ctx, s, uri, params, page)
+		return lr.Result.Items, err
+	})
+	if err != nil {
+		return items, err
+	}
+	return append(items, rest...), nil
+}
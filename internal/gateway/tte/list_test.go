@@ -0,0 +1,44 @@
+package tte
+
+import (
+	"context"
+	"testing"
+)
+
+func TestListStreamsEveryPage(t *testing.T) {
+	srv := newFakeTTE(t, 250)
+	s := Session{id: "sess-1", client: NewClient(quietLog, NewMemoryDB(), "test-key", WithBaseURL(srv.URL)), log: quietLog}
+
+	var n int
+	for ev, err := range List[ConventionEvent](context.Background(), s, "/api/convention/con-1/events", nil) {
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if ev.EventNumber != n {
+			t.Fatalf("event %d out of order: %d", n, ev.EventNumber)
+		}
+		n++
+	}
+	if n != 250 {
+		t.Fatalf("got %d events, want 250", n)
+	}
+}
+
+func TestListStopsEarly(t *testing.T) {
+	srv := newFakeTTE(t, 250)
+	s := Session{id: "sess-1", client: NewClient(quietLog, NewMemoryDB(), "test-key", WithBaseURL(srv.URL)), log: quietLog}
+
+	var n int
+	for _, err := range List[ConventionEvent](context.Background(), s, "/api/convention/con-1/events", nil) {
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		n++
+		if n == 5 {
+			break
+		}
+	}
+	if n != 5 {
+		t.Fatalf("got %d events, want 5", n)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package tte
+
+// lockFile is a no-op where flock is unavailable; writes are still atomic
+// but concurrent processes are not serialized.
+func lockFile(path string, exclusive bool) (unlock func(), err error) {
+	return func() {}, nil
+}
@@ -0,0 +1,153 @@
+package tte
+
+import (
+	"net/http"
+	"time"
+)
+
+// ClientOption configures a Client built by NewClient or RestoreClient.
+type ClientOption func(*Client)
+
+// WithBaseURL points the client at a host other than tabletop.events, such
+// as a staging server, a proxy or an httptest.Server.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient replaces the http.Client used for every request.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// WithTransport sets the RoundTripper of the client's http.Client.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		hc := c.httpClient()
+		hc.Transport = rt
+		c.http = hc
+	}
+}
+
+// WithTimeout sets the overall timeout of each http request.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		hc := c.httpClient()
+		hc.Timeout = d
+		c.http = hc
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) ClientOption {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(rp RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = rp
+	}
+}
+
+// WithRateLimit caps the client at perSecond requests, allowing bursts of
+// up to burst requests. A non-positive perSecond disables rate limiting.
+func WithRateLimit(perSecond float64, burst int) ClientOption {
+	return func(c *Client) {
+		c.limiter = newTokenBucket(perSecond, burst)
+	}
+}
+
+// WithPageWorkers sets how many pages of a paged list are fetched at once.
+// Every worker still goes through the client's rate limiter.
+func WithPageWorkers(n int) ClientOption {
+	return func(c *Client) {
+		c.pageWorkers = n
+	}
+}
+
+// WithCredentialProvider lets sessions log in again on their own when
+// tabletop.events reports them expired.
+func WithCredentialProvider(cp CredentialProvider) ClientOption {
+	return func(c *Client) {
+		c.credentials = cp
+	}
+}
+
+// WithSecretStore keeps the api key and session in ss instead of the
+// default PlainSecretStore.
+func WithSecretStore(ss SecretStore) ClientOption {
+	return func(c *Client) {
+		c.secrets = ss
+	}
+}
+
+// WithProfile keeps the client's api key and session with the named
+// profile instead of the DefaultProfile.
+func WithProfile(name string) ClientOption {
+	return func(c *Client) {
+		c.profile = name
+	}
+}
+
+// WithCachePolicies replaces DefaultCachePolicies.
+func WithCachePolicies(cp CachePolicies) ClientOption {
+	return func(c *Client) {
+		c.cachePolicies = cp
+	}
+}
+
+// WithFullRefreshInterval sets how often RefreshConventionEvents fetches
+// every event to catch deleted ones.
+func WithFullRefreshInterval(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.fullRefresh = d
+	}
+}
+
+// WithHTTPCache turns the CachingTransport wrapped around the client's
+// transport on or off. It is on by default.
+func WithHTTPCache(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.httpCache = enabled
+	}
+}
+
+// WithTimeZone sets the time zone event times are resolved in, by default
+// the local one. tabletop.events gives daypart times without a zone.
+func WithTimeZone(loc *time.Location) ClientOption {
+	return func(c *Client) {
+		if loc != nil {
+			c.location = loc
+		}
+	}
+}
+
+func withOffline() ClientOption {
+	return func(c *Client) {
+		c.offline = true
+	}
+}
+
+// offlineTransport fails every request, leaving the CachingTransport above
+// it to answer what it can.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, ErrOffline
+}
+
+// httpClient returns a copy of the client's http.Client so options never
+// mutate a client shared with other code (e.g. http.DefaultClient).
+func (c *Client) httpClient() *http.Client {
+	if c.http == nil {
+		return &http.Client{}
+	}
+	hc := *c.http
+	return &hc
+}
@@ -0,0 +1,92 @@
+package tte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const defaultPageWorkers = 4
+
+// PageError reports which page of a paged list failed to load.
+type PageError struct {
+	Page int
+	Err  error
+}
+
+func (pe *PageError) Error() string {
+	return fmt.Sprintf("page %d: %s", pe.Page, pe.Err)
+}
+
+func (pe *PageError) Unwrap() error {
+	return pe.Err
+}
+
+// fetchRemainingPages fetches pages 2 through totalPages with a pool of up
+// to workers goroutines and returns their items in page order. The first
+// failing page cancels the rest and is returned as a *PageError.
+func fetchRemainingPages[T any](ctx context.Context, workers int, totalPages int, fetch func(ctx context.Context, page int) ([]T, error)) (items []T, err error) {
+	if totalPages < 2 {
+		return nil, nil
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]T, totalPages+1)
+	errs := make([]error, totalPages+1)
+	pages := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(workers, totalPages-1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range pages {
+				results[page], errs[page] = fetch(ctx, page)
+				if errs[page] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+
+feed:
+	for page := 2; page <= totalPages; page++ {
+		select {
+		case pages <- page:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(pages)
+	wg.Wait()
+
+	// prefer a real failure over the cancellations it caused in other pages
+	var firstErr error
+	for page := 2; page <= totalPages; page++ {
+		if errs[page] == nil {
+			continue
+		}
+		pe := &PageError{Page: page, Err: errs[page]}
+		if !errors.Is(errs[page], context.Canceled) {
+			return nil, pe
+		}
+		if firstErr == nil {
+			firstErr = pe
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, r := range results[2:] {
+		items = append(items, r...)
+	}
+	return items, nil
+}
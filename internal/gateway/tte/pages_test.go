@@ -0,0 +1,41 @@
+package tte
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestFetchRemainingPagesKeepsPageOrder(t *testing.T) {
+	items, err := fetchRemainingPages(context.Background(), 4, 20, func(ctx context.Context, page int) ([]int, error) {
+		time.Sleep(time.Duration(rand.IntN(3)) * time.Millisecond)
+		return []int{page * 10, page*10 + 1}, nil
+	})
+	if err != nil {
+		t.Fatalf("fetchRemainingPages: %v", err)
+	}
+	if len(items) != 38 {
+		t.Fatalf("got %d items, want 38", len(items))
+	}
+	for i := 1; i < len(items); i++ {
+		if items[i] <= items[i-1] {
+			t.Fatalf("items out of order at %d: %v", i, items)
+		}
+	}
+}
+
+func TestFetchRemainingPagesReportsFailedPage(t *testing.T) {
+	boom := errors.New("boom")
+	_, err := fetchRemainingPages(context.Background(), 3, 10, func(ctx context.Context, page int) ([]int, error) {
+		if page == 5 {
+			return nil, boom
+		}
+		return []int{page}, nil
+	})
+	var pe *PageError
+	if !errors.As(err, &pe) || pe.Page != 5 || !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want page 5 to fail with boom", err)
+	}
+}
@@ -0,0 +1,35 @@
+package tte
+
+import "testing"
+
+func TestProfilesKeepSeparateCredentials(t *testing.T) {
+	db := NewMemoryDB()
+	NewClient(quietLog, db, "personal-key")
+	NewClient(quietLog, db, "gm-key", WithProfile("gm"))
+
+	for profile, want := range map[string]string{DefaultProfile: "personal-key", "gm": "gm-key"} {
+		c, err := RestoreClient(quietLog, db, WithProfile(profile))
+		if err != nil {
+			t.Fatalf("RestoreClient(%s): %v", profile, err)
+		}
+		if c.key != want {
+			t.Errorf("profile %s key = %q, want %q", profile, c.key, want)
+		}
+	}
+
+	names, err := Profiles(db)
+	if err != nil {
+		t.Fatalf("Profiles: %v", err)
+	}
+	if len(names) != 2 || names[0] != DefaultProfile || names[1] != "gm" {
+		t.Fatalf("profiles = %v", names)
+	}
+}
+
+func TestProfileRejectsPathNames(t *testing.T) {
+	for _, name := range []string{"../escape", "a/b", ".hidden"} {
+		if _, err := ProfileDB(NewMemoryDB(), name); err == nil {
+			t.Errorf("ProfileDB(%q) was accepted", name)
+		}
+	}
+}
@@ -0,0 +1,476 @@
+package tte
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryEnv holds what a query needs to know beyond the events themselves.
+// Fields that need a missing part of it match nothing.
+type QueryEnv struct {
+	// TypeNames maps event type uris to their names.
+	TypeNames map[string]string
+	Times     Timetable
+	Likes     Likes
+}
+
+// QueryError is a query that cannot be parsed, and where.
+type QueryError struct {
+	Query string
+	// Pos is the byte offset of the problem in Query.
+	Pos int
+	Msg string
+}
+
+func (qe *QueryError) Error() string {
+	return fmt.Sprintf("query: %s at column %d: %s\n  %s\n  %s^", qe.Msg, qe.Pos+1, snippetAt(qe.Query, qe.Pos), qe.Query, strings.Repeat(" ", qe.Pos))
+}
+
+func snippetAt(q string, pos int) string {
+	if pos >= len(q) {
+		return "end of query"
+	}
+	return strconv.Quote(strings.Fields(q[pos:] + " ")[0])
+}
+
+// CompileQuery parses q into a predicate. Terms are ANDed unless joined by
+// OR; NOT or a leading - negates a term, and parentheses group them:
+//
+//	type:RPG day:friday gm:"smith" duration<=240 seats>0 -cancelled (publisher:wizkids OR complexity:light)
+//
+// field:value matches text fields containing value, ignoring case; = and !=
+// match them exactly. Number fields compare with =, !=, <, <=, > and >=,
+// start compares the time of day, e.g. start>=18:00. A flag such as
+// cancelled or liked stands alone, and any other bare or quoted word
+// matches names and descriptions. An empty query matches every event.
+func CompileQuery(q string, env QueryEnv) (p EventPredicate, err error) {
+	toks, err := lexQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	ps := queryParser{query: q, toks: toks, env: env}
+	if ps.peek().kind == tokEOF {
+		return func(ConventionEvent) bool { return true }, nil
+	}
+	if p, err = ps.or(); err != nil {
+		return nil, err
+	}
+	if t := ps.peek(); t.kind != tokEOF {
+		return nil, ps.errorf(t, "unexpected %s", t)
+	}
+	return p, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokNot
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// queryOps are the comparison operators, longest first.
+var queryOps = []string{"<=", ">=", "!=", ":", "=", "<", ">"}
+
+func lexQuery(q string) (toks []token, err error) {
+	isSpace := func(b byte) bool {
+		return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+	}
+	isWord := func(b byte) bool {
+		return !isSpace(b) && !strings.ContainsRune(`()":<>=!`, rune(b))
+	}
+	for i := 0; i < len(q); {
+		r := q[i]
+		switch {
+		case isSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case r == '-' && (len(toks) == 0 || toks[len(toks)-1].kind != tokOp):
+			toks = append(toks, token{tokNot, "-", i})
+			i++
+		case r == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(q) && q[j] != '"'; j++ {
+				if q[j] == '\\' && j+1 < len(q) {
+					j++
+				}
+				b.WriteByte(q[j])
+			}
+			if j == len(q) {
+				return nil, &QueryError{Query: q, Pos: i, Msg: "unterminated quote"}
+			}
+			toks = append(toks, token{tokString, b.String(), i})
+			i = j + 1
+		case len(toks) > 0 && toks[len(toks)-1].kind == tokOp:
+			// a value may hold operator characters, e.g. start>=18:00
+			j := i
+			for j < len(q) && !isSpace(q[j]) && !strings.ContainsRune(`()"`, rune(q[j])) {
+				j++
+			}
+			if j == i {
+				return nil, &QueryError{Query: q, Pos: i, Msg: fmt.Sprintf("unexpected %q", r)}
+			}
+			toks = append(toks, token{tokWord, q[i:j], i})
+			i = j
+		default:
+			if op := opAt(q[i:]); len(op) > 0 {
+				toks = append(toks, token{tokOp, op, i})
+				i += len(op)
+				continue
+			}
+			if !isWord(r) {
+				return nil, &QueryError{Query: q, Pos: i, Msg: fmt.Sprintf("unexpected %q", r)}
+			}
+			j := i
+			for j < len(q) && isWord(q[j]) {
+				j++
+			}
+			toks = append(toks, token{tokWord, q[i:j], i})
+			i = j
+		}
+	}
+	return append(toks, token{tokEOF, "", len(q)}), nil
+}
+
+func opAt(s string) string {
+	for _, op := range queryOps {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+type queryParser struct {
+	query string
+	toks  []token
+	next  int
+	env   QueryEnv
+}
+
+func (ps *queryParser) peek() token {
+	return ps.toks[ps.next]
+}
+
+func (ps *queryParser) take() token {
+	t := ps.toks[ps.next]
+	if t.kind != tokEOF {
+		ps.next++
+	}
+	return t
+}
+
+func (ps *queryParser) errorf(t token, format string, args ...any) error {
+	return &QueryError{Query: ps.query, Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func isKeyword(t token, kw string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, kw)
+}
+
+// or = and { OR and }
+func (ps *queryParser) or() (p EventPredicate, err error) {
+	if p, err = ps.and(); err != nil {
+		return nil, err
+	}
+	for isKeyword(ps.peek(), "or") {
+		ps.take()
+		var q EventPredicate
+		if q, err = ps.and(); err != nil {
+			return nil, err
+		}
+		p = orPredicate(p, q)
+	}
+	return p, nil
+}
+
+// and = not { [AND] not }
+func (ps *queryParser) and() (p EventPredicate, err error) {
+	if p, err = ps.not(); err != nil {
+		return nil, err
+	}
+	for {
+		t := ps.peek()
+		if isKeyword(t, "and") {
+			ps.take()
+		} else if t.kind == tokEOF || t.kind == tokRParen || isKeyword(t, "or") {
+			return p, nil
+		}
+		var q EventPredicate
+		if q, err = ps.not(); err != nil {
+			return nil, err
+		}
+		p = andPredicate(p, q)
+	}
+}
+
+// not = (NOT | -) not | term
+func (ps *queryParser) not() (p EventPredicate, err error) {
+	if t := ps.peek(); t.kind == tokNot || isKeyword(t, "not") {
+		ps.take()
+		negated, err := ps.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(e ConventionEvent) bool { return !negated(e) }, nil
+	}
+	return ps.term()
+}
+
+// term = ( or ) | field op value | flag | text
+func (ps *queryParser) term() (p EventPredicate, err error) {
+	t := ps.take()
+	switch t.kind {
+	case tokLParen:
+		if p, err = ps.or(); err != nil {
+			return nil, err
+		}
+		if c := ps.take(); c.kind != tokRParen {
+			return nil, ps.errorf(c, "expected ) to close the ( at column %d, got %s", t.pos+1, c)
+		}
+		return p, nil
+	case tokString:
+		return textPredicate(t.text), nil
+	case tokWord:
+		if isKeyword(t, "or") || isKeyword(t, "and") {
+			return nil, ps.errorf(t, "%s needs a term before it", strings.ToUpper(t.text))
+		}
+	default:
+		return nil, ps.errorf(t, "expected a term, got %s", t)
+	}
+
+	op := ps.peek()
+	name := strings.ToLower(t.text)
+	f, known := queryFields[name]
+	if op.kind != tokOp {
+		if known && f.flag != nil {
+			env := ps.env
+			return func(e ConventionEvent) bool { return f.flag(env, e) }, nil
+		}
+		return textPredicate(t.text), nil
+	}
+	ps.take()
+	if !known {
+		return nil, ps.errorf(t, "unknown field %q, expected one of %s", t.text, strings.Join(queryFieldNames(), ", "))
+	}
+	v := ps.take()
+	if v.kind != tokWord && v.kind != tokString {
+		return nil, ps.errorf(v, "expected a value after %s%s, got %s", t.text, op.text, v)
+	}
+	return f.compile(ps, name, op, v)
+}
+
+func andPredicate(p, q EventPredicate) EventPredicate {
+	return func(e ConventionEvent) bool { return p(e) && q(e) }
+}
+
+func orPredicate(p, q EventPredicate) EventPredicate {
+	return func(e ConventionEvent) bool { return p(e) || q(e) }
+}
+
+// textPredicate matches events whose name or descriptions contain s.
+func textPredicate(s string) EventPredicate {
+	s = strings.ToLower(s)
+	return func(e ConventionEvent) bool {
+		for _, t := range []string{e.Name, e.Description, e.LongDescription} {
+			if strings.Contains(strings.ToLower(t), s) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// queryField is one of text, number, clock or flag.
+type queryField struct {
+	text   func(env QueryEnv, e ConventionEvent) []string
+	number func(env QueryEnv, e ConventionEvent) (n int, ok bool)
+	clock  func(env QueryEnv, e ConventionEvent) (d time.Duration, ok bool)
+	flag   func(env QueryEnv, e ConventionEvent) bool
+}
+
+func (f queryField) compile(ps *queryParser, name string, op token, v token) (p EventPredicate, err error) {
+	env := ps.env
+	switch {
+	case f.text != nil:
+		want := strings.ToLower(v.text)
+		match := func(s string) bool { return strings.Contains(strings.ToLower(s), want) }
+		switch op.text {
+		case "=", "!=":
+			match = func(s string) bool { return strings.EqualFold(s, want) }
+		case ":":
+		default:
+			return nil, ps.errorf(op, "%s is text and cannot be compared with %s", name, op.text)
+		}
+		return func(e ConventionEvent) bool {
+			var matched bool
+			for _, s := range f.text(env, e) {
+				matched = matched || match(s)
+			}
+			return matched == (op.text != "!=")
+		}, nil
+	case f.number != nil:
+		n, err := strconv.Atoi(v.text)
+		if err != nil {
+			return nil, ps.errorf(v, "%s needs a whole number, got %s", name, v)
+		}
+		cmp := compareWith(op.text)
+		return func(e ConventionEvent) bool {
+			got, ok := f.number(env, e)
+			return ok && cmp(got-n)
+		}, nil
+	case f.clock != nil:
+		d, err := ParseClock(v.text)
+		if err != nil {
+			return nil, ps.errorf(v, "%s needs a time of day such as 18:00 or 6:00 PM, got %s", name, v)
+		}
+		cmp := compareWith(op.text)
+		return func(e ConventionEvent) bool {
+			got, ok := f.clock(env, e)
+			return ok && cmp(int(got-d))
+		}, nil
+	}
+	if op.text != ":" && op.text != "=" && op.text != "!=" {
+		return nil, ps.errorf(op, "%s is a flag and cannot be compared with %s", name, op.text)
+	}
+	want, ok := map[string]bool{"true": true, "yes": true, "false": false, "no": false}[strings.ToLower(v.text)]
+	if !ok {
+		return nil, ps.errorf(v, "%s is a flag and needs true or false, got %s", name, v)
+	}
+	want = want == (op.text != "!=")
+	return func(e ConventionEvent) bool { return f.flag(env, e) == want }, nil
+}
+
+// compareWith returns whether a difference satisfies op.
+func compareWith(op string) func(diff int) bool {
+	switch op {
+	case "!=":
+		return func(d int) bool { return d != 0 }
+	case "<":
+		return func(d int) bool { return d < 0 }
+	case "<=":
+		return func(d int) bool { return d <= 0 }
+	case ">":
+		return func(d int) bool { return d > 0 }
+	case ">=":
+		return func(d int) bool { return d >= 0 }
+	}
+	return func(d int) bool { return d == 0 }
+}
+
+func textField(get func(e ConventionEvent) string) queryField {
+	return queryField{text: func(_ QueryEnv, e ConventionEvent) []string { return []string{get(e)} }}
+}
+
+func numberField(get func(e ConventionEvent) int) queryField {
+	return queryField{number: func(_ QueryEnv, e ConventionEvent) (int, bool) { return get(e), true }}
+}
+
+func flagField(get func(e ConventionEvent) bool) queryField {
+	return queryField{flag: func(_ QueryEnv, e ConventionEvent) bool { return get(e) }}
+}
+
+// queryFields are the fields a query can name, with their aliases.
+var queryFields = map[string]queryField{
+	"name":        textField(func(e ConventionEvent) string { return e.Name }),
+	"id":          textField(func(e ConventionEvent) string { return e.ID }),
+	"gm":          textField(func(e ConventionEvent) string { return e.CustomFields.GM }),
+	"publisher":   textField(func(e ConventionEvent) string { return e.CustomFields.Publisher }),
+	"host":        textField(func(e ConventionEvent) string { return e.CustomFields.HostingGroup }),
+	"complexity":  textField(func(e ConventionEvent) string { return e.CustomFields.Complexity }),
+	"category":    textField(func(e ConventionEvent) string { return e.CustomFields.SubCategory }),
+	"room":        textField(func(e ConventionEvent) string { return e.RoomName }),
+	"age":         textField(func(e ConventionEvent) string { return e.AgeRange }),
+	"description": {text: func(_ QueryEnv, e ConventionEvent) []string { return []string{e.Description, e.LongDescription} }},
+	"type": {text: func(env QueryEnv, e ConventionEvent) []string {
+		return []string{env.TypeNames[e.Relationships.Type]}
+	}},
+	"day": {text: func(env QueryEnv, e ConventionEvent) []string {
+		if t, ok := env.Times.Get(e); ok {
+			return []string{t.Start.Format("Monday")}
+		}
+		day, _, _ := e.StartdaypartName.Split()
+		return []string{day}
+	}},
+
+	"number":   numberField(func(e ConventionEvent) int { return e.EventNumber }),
+	"duration": numberField(func(e ConventionEvent) int { return e.Duration }),
+	"seats":    numberField(func(e ConventionEvent) int { return e.AvailableQuantity }),
+	"tickets":  numberField(func(e ConventionEvent) int { return e.MaxTickets }),
+	"sold":     numberField(func(e ConventionEvent) int { return e.SoldCount }),
+	"waiting":  numberField(func(e ConventionEvent) int { return e.WaitCount }),
+	"price":    numberField(func(e ConventionEvent) int { return e.Price }),
+	"priority": {number: func(env QueryEnv, e ConventionEvent) (int, bool) {
+		l, ok := env.Likes.Get(e.ViewURI)
+		return l.Priority, ok
+	}},
+
+	"start": {clock: func(env QueryEnv, e ConventionEvent) (time.Duration, bool) {
+		t, ok := env.Times.Get(e)
+		return sinceMidnight(t.Start), ok
+	}},
+
+	"cancelled":  flagField(func(e ConventionEvent) bool { return e.IsCancelled != 0 }),
+	"soldout":    flagField(ConventionEvent.SoldOut),
+	"tournament": flagField(func(e ConventionEvent) bool { return e.IsTournament != 0 }),
+	"online":     flagField(func(e ConventionEvent) bool { return e.IsOnline != 0 }),
+	"liked":      {flag: func(env QueryEnv, e ConventionEvent) bool { return env.Likes.Has(e.ViewURI) }},
+}
+
+func init() {
+	for alias, name := range map[string]string{
+		"title": "name", "desc": "description", "group": "host", "mins": "duration",
+		"available": "seats", "canceled": "cancelled", "full": "soldout",
+	} {
+		queryFields[alias] = queryFields[name]
+	}
+}
+
+func queryFieldNames() (names []string) {
+	for name := range queryFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseClock reads a time of day such as 9:00, 21:30 or 9:00 PM as the
+// time since midnight.
+func ParseClock(s string) (d time.Duration, err error) {
+	var t time.Time
+	for _, layout := range []string{"15:04", "3:04 PM", "3:04PM", "3PM", "3 PM"} {
+		if t, err = time.Parse(layout, strings.ToUpper(strings.TrimSpace(s))); err == nil {
+			return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+		}
+	}
+	return d, fmt.Errorf("unrecognized time of day %q", s)
+}
@@ -0,0 +1,93 @@
+package tte
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileQueryMatchesFields(t *testing.T) {
+	rpg, board := "/api/eventtype/rpg", "/api/eventtype/board"
+	env := QueryEnv{
+		TypeNames: map[string]string{rpg: "RPG", board: "Board Game"},
+		Times: Timetable{
+			"dnd":    {Start: time.Date(2025, 8, 1, 19, 0, 0, 0, time.UTC)},
+			"coc":    {Start: time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)},
+			"catan":  {Start: time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)},
+			"heroes": {Start: time.Date(2025, 8, 2, 10, 0, 0, 0, time.UTC)},
+		},
+		Likes: Likes{{ViewURI: "/e/catan", Priority: 5}},
+	}
+	ez := FilterableConventionEvents{
+		{ID: "dnd", ViewURI: "/e/dnd", Name: "Dungeons & Dragons", Duration: 240, AvailableQuantity: 2, MaxTickets: 6,
+			Relationships: ConventionEventRelationships{Type: rpg}},
+		{ID: "coc", ViewURI: "/e/coc", Name: "Call of Cthulhu", Duration: 180, MaxTickets: 5,
+			Relationships: ConventionEventRelationships{Type: rpg}},
+		{ID: "catan", ViewURI: "/e/catan", Name: "Catan", Duration: 120, AvailableQuantity: 3, MaxTickets: 4,
+			Relationships: ConventionEventRelationships{Type: board}},
+		{ID: "heroes", ViewURI: "/e/heroes", Name: "HeroClix", Duration: 300, AvailableQuantity: 8, IsCancelled: 1,
+			Relationships: ConventionEventRelationships{Type: board}},
+	}
+	ez[0].CustomFields.GM = "Jane Smith"
+	ez[2].CustomFields.Publisher = "Catan Studio"
+	ez[3].CustomFields.Publisher = "WizKids"
+
+	for _, tc := range []struct {
+		query string
+		want  string
+	}{
+		{``, "dnd coc catan heroes"},
+		{`type:RPG day:friday gm:"smith" duration<=240 seats>0 -cancelled`, "dnd"},
+		{`-cancelled (publisher:wizkids OR type="board game")`, "catan"},
+		{`publisher:wizkids OR complexity:light`, "heroes"},
+		{`type:rpg soldout`, "coc"},
+		{`start>=18:00 OR start<9:30am`, "dnd coc"},
+		{`liked priority>=4`, "catan"},
+		{`NOT liked AND day:sat`, "heroes"},
+		{`"call of" OR dragons`, "dnd coc"},
+		{`gm!=smith type:rpg`, "dnd coc"},
+		{`cancelled:no duration>100 duration<200`, "coc catan"},
+	} {
+		p, err := CompileQuery(tc.query, env)
+		if err != nil {
+			t.Errorf("%s: %v", tc.query, err)
+			continue
+		}
+		var got []string
+		for _, e := range ez.Filter(p) {
+			got = append(got, e.ID)
+		}
+		if strings.Join(got, " ") != tc.want {
+			t.Errorf("%s matched %v, want %s", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestCompileQueryReportsWhereItFails(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{`colour:red`, 0, `unknown field "colour"`},
+		{`duration<=long`, 10, "needs a whole number"},
+		{`gm>smith`, 2, "is text and cannot be compared with >"},
+		{`(type:rpg OR seats>0`, 20, "expected ) to close the ( at column 1"},
+		{`name:"catan`, 5, "unterminated quote"},
+		{`OR catan`, 0, "OR needs a term before it"},
+		{`start>=late`, 7, "needs a time of day"},
+		{`catan)`, 5, `unexpected ")"`},
+		{`seats>`, 6, "expected a value after seats>"},
+	} {
+		_, err := CompileQuery(tc.query, QueryEnv{})
+		var qe *QueryError
+		if !errors.As(err, &qe) {
+			t.Errorf("%s: err = %v", tc.query, err)
+			continue
+		}
+		if qe.Pos != tc.pos || !strings.Contains(qe.Msg, tc.msg) {
+			t.Errorf("%s: error at %d %q, want %d %q", tc.query, qe.Pos, qe.Msg, tc.pos, tc.msg)
+		}
+	}
+}
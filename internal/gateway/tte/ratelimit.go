@@ -0,0 +1,66 @@
+package tte
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a client-wide rate limiter. Tokens refill continuously at
+// rate per second up to burst; every request takes one.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(perSecond float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: perSecond, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// reserve takes a token and reports how long the caller must wait before
+// using it.
+func (tb *tokenBucket) reserve() time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := time.Now()
+	tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.last = now
+	tb.tokens--
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+// wait blocks until a token is available or ctx is done. A nil or
+// non-positive rate bucket never blocks.
+func (tb *tokenBucket) wait(ctx context.Context) error {
+	if tb == nil || tb.rate <= 0 {
+		return nil
+	}
+	return sleep(ctx, tb.reserve())
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
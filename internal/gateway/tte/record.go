@@ -0,0 +1,156 @@
+package tte
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// Record is the envelope every cached JSON record is stored in.
+type Record struct {
+	// Schema is the version of the layout of Data.
+	Schema    int             `json:"schema"`
+	FetchedAt time.Time       `json:"fetched_at"`
+	Source    string          `json:"source"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Migration upgrades the data of a record by one schema version.
+type Migration func(data json.RawMessage) (json.RawMessage, error)
+
+// RecordSchema is the current schema of a cached kind and the migrations
+// that lead to it; Migrations[v] upgrades version v to v+1.
+type RecordSchema struct {
+	Version    int
+	Migrations []Migration
+}
+
+// ErrIncompatibleRecord is returned when a cached record cannot be migrated
+// to the current schema. The record is deleted and the error matches
+// fs.ErrNotExist so callers fetch it again.
+var ErrIncompatibleRecord = errors.New("cache record has an incompatible schema")
+
+// recordSchemas holds the schema of each cached kind. Version 0 is the bare
+// JSON stored before records had an envelope.
+var recordSchemas = map[string]RecordSchema{
+	CacheConventions: {Version: 1, Migrations: []Migration{wrapLegacy}},
+	CacheEvents:      {Version: 1, Migrations: []Migration{wrapLegacy}},
+	CacheEventType:   {Version: 1, Migrations: []Migration{wrapLegacy}},
+	CacheSnapshot:    {Version: 1, Migrations: []Migration{wrapLegacy}},
+	CacheHTTP:        {Version: 1, Migrations: []Migration{wrapLegacy}},
+	CacheDayparts:    {Version: 1, Migrations: []Migration{wrapLegacy}},
+	CacheLikes:       {Version: 1, Migrations: []Migration{wrapLegacy}},
+}
+
+// wrapLegacy moves a bare record into an envelope; the data itself kept its
+// layout.
+func wrapLegacy(data json.RawMessage) (json.RawMessage, error) {
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("legacy record is not valid json")
+	}
+	return data, nil
+}
+
+// storeRecord stores v under the current schema of id, fetched from source.
+func storeRecord(db DB, id string, kind string, source string, v any) (err error) {
+	r := Record{
+		Schema:    recordSchemas[id].Version,
+		FetchedAt: time.Now().UTC(),
+		Source:    source,
+	}
+	if r.Data, err = json.Marshal(v); err != nil {
+		return err
+	}
+	var b []byte
+	if b, err = json.Marshal(r); err != nil {
+		return err
+	}
+	return db.Store(id, kind, "json", b)
+}
+
+// readRecord reads the record of id into v, migrating it to the current
+// schema first. An upgraded record is written back when possible; one that
+// cannot be upgraded or decoded is deleted.
+func readRecord(db DB, id string, kind string, v any) (r Record, err error) {
+	var b []byte
+	if b, err = db.Read(id, kind, "json"); err != nil {
+		return r, err
+	}
+	if r, err = unmarshalRecord(db, id, kind, b, v); err != nil {
+		db.Delete(id, kind, "json")
+		return r, fmt.Errorf("%w: %s: %w (%w)", ErrIncompatibleRecord, newKey(id, kind, "json"), err, fs.ErrNotExist)
+	}
+	return r, nil
+}
+
+// unmarshalRecord decodes b, the stored record of id, into v like
+// readRecord but leaves a record it cannot decode in place.
+func unmarshalRecord(db DB, id string, kind string, b []byte, v any) (r Record, err error) {
+	if r, err = decodeRecord(b); err != nil {
+		return r, err
+	}
+	var migrated bool
+	if migrated, err = r.migrate(recordSchemas[id]); err != nil {
+		return r, err
+	}
+	if migrated {
+		if r.FetchedAt.IsZero() {
+			if age, e := db.CacheAge(id, kind, "json"); e == nil {
+				r.FetchedAt = time.Now().Add(-age).UTC()
+			}
+		}
+		storeMigrated(db, id, kind, r)
+	}
+	return r, json.Unmarshal(r.Data, v)
+}
+
+// decodeRecord decodes an envelope, treating anything that is not one as a
+// bare version 0 record.
+func decodeRecord(b []byte) (r Record, err error) {
+	var probe map[string]json.RawMessage
+	if err = json.Unmarshal(b, &probe); err != nil {
+		return r, err
+	}
+	if _, ok := probe["schema"]; !ok {
+		return Record{Data: bytes.Clone(b)}, nil
+	}
+	err = json.Unmarshal(b, &r)
+	return r, err
+}
+
+func (r *Record) migrate(schema RecordSchema) (migrated bool, err error) {
+	if r.Schema > schema.Version {
+		return false, fmt.Errorf("schema %d is newer than %d", r.Schema, schema.Version)
+	}
+	for r.Schema < schema.Version {
+		if r.Schema < 0 || r.Schema >= len(schema.Migrations) || schema.Migrations[r.Schema] == nil {
+			return migrated, fmt.Errorf("no migration from schema %d", r.Schema)
+		}
+		if r.Data, err = schema.Migrations[r.Schema](r.Data); err != nil {
+			return migrated, fmt.Errorf("migrating schema %d: %w", r.Schema, err)
+		}
+		r.Schema++
+		migrated = true
+	}
+	return migrated, nil
+}
+
+// recordAge is the time since r was fetched, falling back to the age of the
+// stored record for one migrated from before records had an envelope.
+func recordAge(db DB, r Record, id string, kind string) (time.Duration, error) {
+	if !r.FetchedAt.IsZero() {
+		return time.Since(r.FetchedAt), nil
+	}
+	return db.CacheAge(id, kind, "json")
+}
+
+func storeMigrated(db DB, id string, kind string, r Record) (err error) {
+	var b []byte
+	if b, err = json.Marshal(r); err != nil {
+		return err
+	}
+	return db.Store(id, kind, "json", b)
+}
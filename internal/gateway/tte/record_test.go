@@ -0,0 +1,100 @@
+package tte
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestRecordRoundTrip(t *testing.T) {
+	db := NewMemoryDB()
+	in := ConventionEvents{Items: []ConventionEvent{{ID: "e1", Name: "Catan"}}}
+	if err := storeRecord(db, CacheEvents, "/convention/x", "https://tabletop.events/api/convention/1/events", in); err != nil {
+		t.Fatal(err)
+	}
+	var out ConventionEvents
+	r, err := readRecord(db, CacheEvents, "/convention/x", &out)
+	if err != nil {
+		t.Fatalf("readRecord: %v", err)
+	}
+	if r.Schema != recordSchemas[CacheEvents].Version || r.FetchedAt.IsZero() || r.Source == "" {
+		t.Fatalf("envelope = %+v", r)
+	}
+	if len(out.Items) != 1 || out.Items[0].Name != "Catan" {
+		t.Fatalf("data = %+v", out)
+	}
+}
+
+func TestLegacyRecordIsMigratedInPlace(t *testing.T) {
+	db := NewMemoryDB()
+	_ = db.Store(CacheEvents, "/convention/x", "json", []byte(`{"items":[{"id":"e1","name":"Catan"}]}`))
+
+	var out ConventionEvents
+	r, err := readRecord(db, CacheEvents, "/convention/x", &out)
+	if err != nil {
+		t.Fatalf("readRecord: %v", err)
+	}
+	if len(out.Items) != 1 || out.Items[0].ID != "e1" {
+		t.Fatalf("data = %+v", out)
+	}
+	if r.FetchedAt.IsZero() {
+		t.Fatalf("migrated record lost its fetch time")
+	}
+
+	b, _ := db.Read(CacheEvents, "/convention/x", "json")
+	var stored Record
+	if err = json.Unmarshal(b, &stored); err != nil || stored.Schema != 1 {
+		t.Fatalf("stored = %s, %v", b, err)
+	}
+}
+
+func TestUnmigratableRecordIsInvalidated(t *testing.T) {
+	defer func(s map[string]RecordSchema) { recordSchemas = s }(recordSchemas)
+	recordSchemas = map[string]RecordSchema{
+		CacheEvents: {Version: 3, Migrations: []Migration{wrapLegacy, nil, nil}},
+	}
+
+	cases := map[string]string{
+		"missing migration": `{"schema":1,"data":{"items":[]}}`,
+		"newer schema":      `{"schema":4,"data":{"items":[]}}`,
+		"undecodable data":  `{"schema":3,"data":{"items":"nope"}}`,
+	}
+	for name, record := range cases {
+		db := NewMemoryDB()
+		_ = db.Store(CacheEvents, "/convention/x", "json", []byte(record))
+
+		var out ConventionEvents
+		_, err := readRecord(db, CacheEvents, "/convention/x", &out)
+		if !errors.Is(err, ErrIncompatibleRecord) || !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+		if _, err = db.Read(CacheEvents, "/convention/x", "json"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s: record was not deleted: %v", name, err)
+		}
+	}
+}
+
+func TestMigrationsRunInOrder(t *testing.T) {
+	defer func(s map[string]RecordSchema) { recordSchemas = s }(recordSchemas)
+	rename := func(data json.RawMessage) (json.RawMessage, error) {
+		var v map[string]any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		v["name"] = v["title"]
+		delete(v, "title")
+		return json.Marshal(v)
+	}
+	recordSchemas = map[string]RecordSchema{
+		CacheEventType: {Version: 2, Migrations: []Migration{wrapLegacy, rename}},
+	}
+
+	db := NewMemoryDB()
+	_ = db.Store(CacheEventType, "/api/eventtype/1", "json", []byte(`{"title":"RPG"}`))
+	var out ConventionEventType
+	r, err := readRecord(db, CacheEventType, "/api/eventtype/1", &out)
+	if err != nil || r.Schema != 2 || out.Name != "RPG" {
+		t.Fatalf("record = %+v, data = %+v, err = %v", r, out, err)
+	}
+}
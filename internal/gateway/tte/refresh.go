@@ -0,0 +1,111 @@
+package tte
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultFullRefreshInterval is how often RefreshConventionEvents fetches
+// every event instead of only the updated ones, to catch deleted events.
+const defaultFullRefreshInterval = 6 * time.Hour
+
+// updatedSinceParam filters the events list to those updated at or after a
+// date_updated. An api that ignores it only makes the refresh less cheap.
+const updatedSinceParam = "date_updated"
+
+// EventRefresh reports what RefreshConventionEvents did.
+type EventRefresh struct {
+	Events []ConventionEvent
+	// Full is set when every event was fetched.
+	Full bool
+	// Updated counts the events fetched by an incremental refresh.
+	Updated int
+}
+
+func (s Session) RefreshConventionEvents(con Convention) (r EventRefresh, err error) {
+	return s.RefreshConventionEventsContext(context.Background(), con)
+}
+
+// RefreshConventionEventsContext fetches only the events of con updated
+// since the newest one in the cache and merges them in. It falls back to
+// GetConventionEventsContext when nothing is cached or the last full
+// refresh is older than the client's full refresh interval.
+func (s Session) RefreshConventionEventsContext(ctx context.Context, con Convention) (r EventRefresh, err error) {
+	var cached ConventionEvents
+	_, err = readRecord(s.client.db, CacheEvents, con.ViewURI, &cached)
+	since := newestUpdate(cached.Items)
+	if err != nil || len(since) == 0 || time.Since(cached.Refreshed) > s.client.fullRefresh {
+		r.Full = true
+		r.Events, err = s.GetConventionEventsContext(ctx, con)
+		return r, err
+	}
+
+	uri := fmt.Sprintf("/api/convention/%s/events", con.ID)
+	var updated []ConventionEvent
+	updated, err = ListAll[ConventionEvent](ctx, s, uri, map[string]string{
+		"_include_relationships": "1",
+		updatedSinceParam:        ">=" + since,
+	})
+	if err != nil {
+		return r, err
+	}
+	r.Updated = len(updated)
+	r.Events = mergeEvents(cached.Items, updated)
+	s.log.Debug("refreshed convention events", "convention", con.ViewURI, "since", since, "updated", r.Updated)
+
+	// every refresh is stored, so fields DiffEvents ignores are kept and the
+	// cache is fresh again; snapshots are only taken of notable changes
+	source := s.client.sourceURL(uri)
+	changed := len(DiffEvents(cached.Items, r.Events)) > 0
+	cached.Items = r.Events
+	if e := storeRecord(s.client.db, CacheEvents, con.ViewURI, source, cached); e != nil {
+		s.log.Warn("unable to cache convention events", "error", e)
+	}
+	if !changed {
+		return r, nil
+	}
+	if _, e := StoreSnapshot(s.client.db, con, source, r.Events); e != nil {
+		s.log.Warn("unable to store convention events snapshot", "error", e)
+	}
+	return r, nil
+}
+
+// newestUpdate returns the latest date_updated of ez as the api wrote it.
+func newestUpdate(ez []ConventionEvent) (newest string) {
+	var newestAt time.Time
+	for _, e := range ez {
+		at, err := parseTTEDate(e.DateUpdated, time.UTC)
+		if err != nil {
+			continue
+		}
+		if at.After(newestAt) {
+			newest, newestAt = e.DateUpdated, at
+		}
+	}
+	return newest
+}
+
+// mergeEvents replaces the events of cached that were updated, keeping their
+// place, and appends the new ones.
+func mergeEvents(cached []ConventionEvent, updated []ConventionEvent) (merged []ConventionEvent) {
+	byID := make(map[string]ConventionEvent, len(updated))
+	for _, e := range updated {
+		byID[e.ID] = e
+	}
+	merged = make([]ConventionEvent, 0, len(cached)+len(updated))
+	for _, e := range cached {
+		if u, ok := byID[e.ID]; ok {
+			e = u
+			delete(byID, e.ID)
+		}
+		merged = append(merged, e)
+	}
+	for _, e := range updated {
+		if _, ok := byID[e.ID]; ok {
+			merged = append(merged, e)
+			delete(byID, e.ID)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,128 @@
+package tte
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newUpdatingTTE serves the events of one convention, honouring the
+// date_updated filter, and records the filters it was asked for.
+func newUpdatingTTE(t *testing.T, events map[string]ConventionEvent) (srv *httptest.Server, mu *sync.Mutex, filters *[]string) {
+	t.Helper()
+	mu = &sync.Mutex{}
+	filters = &[]string{}
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		since := strings.TrimPrefix(r.URL.Query().Get(updatedSinceParam), ">=")
+		*filters = append(*filters, since)
+		var items []ConventionEvent
+		for _, e := range events {
+			if e.DateUpdated >= since {
+				items = append(items, e)
+			}
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": ConventionEvents{Items: items, Paging: Paging{TotalPages: 1}}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, mu, filters
+}
+
+func TestRefreshConventionEventsMergesUpdates(t *testing.T) {
+	events := map[string]ConventionEvent{
+		"a": {ID: "a", Name: "Catan", DateUpdated: "2025-07-01 10:00:00"},
+		"b": {ID: "b", Name: "Azul", DateUpdated: "2025-07-02 10:00:00"},
+	}
+	srv, mu, filters := newUpdatingTTE(t, events)
+	con := Convention{ID: "con-1", ViewURI: "/convention/test-con"}
+	s := Session{id: "sess-1", client: NewClient(quietLog, NewMemoryDB(), "test-key", WithBaseURL(srv.URL)), log: quietLog}
+
+	r, err := s.RefreshConventionEvents(con)
+	if err != nil || !r.Full || len(r.Events) != 2 {
+		t.Fatalf("first refresh = %+v, %v", r, err)
+	}
+
+	mu.Lock()
+	events["a"] = ConventionEvent{ID: "a", Name: "Catan", IsCancelled: 1, DateUpdated: "2025-07-03 09:00:00"}
+	events["c"] = ConventionEvent{ID: "c", Name: "Root", DateUpdated: "2025-07-03 09:30:00"}
+	delete(events, "b")
+	mu.Unlock()
+
+	r, err = s.RefreshConventionEvents(con)
+	if err != nil || r.Full || r.Updated != 2 {
+		t.Fatalf("incremental refresh = %+v, %v", r, err)
+	}
+	if (*filters)[len(*filters)-1] != "2025-07-02 10:00:00" {
+		t.Fatalf("filtered since %q", (*filters)[len(*filters)-1])
+	}
+	// b was deleted, which only a full refresh notices
+	if len(r.Events) != 3 || r.Events[0].IsCancelled != 1 || r.Events[2].ID != "c" {
+		t.Fatalf("merged events = %+v", r.Events)
+	}
+	cache, err := s.GetCachedConventionEvents(con)
+	if err != nil || len(cache.ConventionEvents) != 3 {
+		t.Fatalf("cached events = %+v, %v", cache.ConventionEvents, err)
+	}
+
+	s.client.fullRefresh = time.Nanosecond
+	r, err = s.RefreshConventionEvents(con)
+	if err != nil || !r.Full || len(r.Events) != 2 {
+		t.Fatalf("full refresh = %+v, %v", r, err)
+	}
+}
+
+func TestMergeEventsKeepsOrder(t *testing.T) {
+	cached := []ConventionEvent{{ID: "1"}, {ID: "2", Name: "old"}, {ID: "3"}}
+	merged := mergeEvents(cached, []ConventionEvent{{ID: "4"}, {ID: "2", Name: "new"}})
+	var ids []string
+	for _, e := range merged {
+		ids = append(ids, e.ID)
+	}
+	if strings.Join(ids, ",") != "1,2,3,4" || merged[1].Name != "new" {
+		t.Fatalf("merged = %+v", merged)
+	}
+}
+
+func TestRefreshConventionEventsStoresUnnotableUpdates(t *testing.T) {
+	events := map[string]ConventionEvent{
+		"a": {ID: "a", Name: "Catan", SoldCount: 1, DateUpdated: "2025-07-01 10:00:00"},
+	}
+	srv, mu, _ := newUpdatingTTE(t, events)
+	con := Convention{ID: "con-1", ViewURI: "/convention/test-con"}
+	db := NewMemoryDB()
+	s := Session{id: "sess-1", client: NewClient(quietLog, db, "test-key", WithBaseURL(srv.URL)), log: quietLog}
+
+	if _, err := s.RefreshConventionEvents(con); err != nil {
+		t.Fatal(err)
+	}
+	taken, _ := Snapshots(db, con)
+
+	mu.Lock()
+	events["a"] = ConventionEvent{ID: "a", Name: "Catan: Seafarers", SoldCount: 3, DateUpdated: "2025-07-02 10:00:00"}
+	mu.Unlock()
+	if r, err := s.RefreshConventionEvents(con); err != nil || r.Full || r.Updated != 1 {
+		t.Fatalf("incremental refresh = %+v, %v", r, err)
+	}
+
+	cache, err := s.GetCachedConventionEvents(con)
+	if err != nil || len(cache.ConventionEvents) != 1 {
+		t.Fatalf("cached events = %+v, %v", cache.ConventionEvents, err)
+	}
+	if e := cache.ConventionEvents[0]; e.Name != "Catan: Seafarers" || e.SoldCount != 3 || e.DateUpdated != "2025-07-02 10:00:00" {
+		t.Errorf("cached event = %+v", e)
+	}
+	if !cache.Fresh {
+		t.Errorf("cache is stale at %s after a refresh", cache.Age)
+	}
+	// neither change is one DiffEvents reports, so no snapshot is taken
+	if again, _ := Snapshots(db, con); len(again) != len(taken) {
+		t.Errorf("snapshots = %v, want %v", again, taken)
+	}
+}
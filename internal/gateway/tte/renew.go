@@ -0,0 +1,91 @@
+package tte
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+)
+
+// CredentialProvider supplies the username and password used to log in
+// again when a session expires mid-run.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (userName string, password string, err error)
+}
+
+// CredentialFunc adapts a function to a CredentialProvider.
+type CredentialFunc func(ctx context.Context) (userName string, password string, err error)
+
+func (f CredentialFunc) Credentials(ctx context.Context) (string, string, error) {
+	return f(ctx)
+}
+
+// sessionAuth is the session id shared by every copy of a Session, so a
+// renewal made by one copy is seen by all of them.
+type sessionAuth struct {
+	mu  sync.Mutex
+	id  string
+	uid string
+}
+
+func newSessionAuth(s Session) *sessionAuth {
+	return &sessionAuth{id: s.id, uid: s.uid}
+}
+
+// get performs a GET as this session. When tabletop.events reports the
+// session expired and the client has a CredentialProvider, it logs in
+// again and replays the request once.
+func (s Session) get(ctx context.Context, uri string, params map[string]string) (body []byte, err error) {
+	id := s.ID()
+	body, err = s.client.httpGet(ctx, uri, withSessionID(params, id), nil)
+	if err != nil || s.client.credentials == nil || s.auth == nil || !isBadSession(body) {
+		return body, err
+	}
+
+	s.log.Info("tabletop.events session expired, renewing", "session_id", id)
+	if err = s.renew(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.client.httpGet(ctx, uri, withSessionID(params, s.ID()), nil)
+}
+
+// renew replaces the session staleID with a new one. Concurrent callers
+// that saw the same stale id share a single login.
+func (s Session) renew(ctx context.Context, staleID string) error {
+	s.auth.mu.Lock()
+	defer s.auth.mu.Unlock()
+	if s.auth.id != staleID {
+		return nil
+	}
+
+	userName, password, err := s.client.credentials.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+	fresh, err := s.client.NewSessionContext(ctx, userName, password)
+	if err != nil {
+		return err
+	}
+	s.auth.id = fresh.id
+	s.auth.uid = fresh.uid
+	return nil
+}
+
+func isBadSession(body []byte) bool {
+	var envelope struct {
+		Err *ApiError `json:"error"`
+	}
+	if decodeResponse(body, &envelope) != nil || envelope.Err == nil {
+		return false
+	}
+	return errors.Is(envelope.Err, ErrSessionExpired) || errors.Is(envelope.Err, ErrAuthRequired)
+}
+
+func withSessionID(params map[string]string, id string) map[string]string {
+	out := maps.Clone(params)
+	if out == nil {
+		out = map[string]string{}
+	}
+	out["session_id"] = id
+	return out
+}
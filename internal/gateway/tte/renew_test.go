@@ -0,0 +1,60 @@
+package tte
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSessionRenewsWhenExpired(t *testing.T) {
+	var logins atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/session", func(w http.ResponseWriter, r *http.Request) {
+		logins.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": Session{id: "sess-2"}})
+	})
+	mux.HandleFunc("GET /api/eventtype/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("session_id") != "sess-2" {
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": ApiError{Code: 441, Message: "session expired"}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": ConventionEventType{Name: "RPG"}})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	creds := CredentialFunc(func(ctx context.Context) (string, string, error) {
+		return "gm", "secret", nil
+	})
+	c := NewClient(quietLog, NewMemoryDB(), "test-key", WithBaseURL(srv.URL), WithCredentialProvider(creds))
+	s := Session{id: "sess-1", client: c, log: quietLog}
+	s.auth = newSessionAuth(s)
+	// sessions are values; copies taken before the renewal follow it too
+	copied := s
+
+	cet, err := s.GetConventionEventTypeContext(context.Background(), "/api/eventtype/rpg")
+	if err != nil {
+		t.Fatalf("GetConventionEventType: %v", err)
+	}
+	if cet.Name != "RPG" {
+		t.Fatalf("event type name = %q, want RPG", cet.Name)
+	}
+	if id := copied.ID(); id != "sess-2" {
+		t.Fatalf("session id = %q, want sess-2", id)
+	}
+	if n := logins.Load(); n != 1 {
+		t.Fatalf("logged in %d times, want 1", n)
+	}
+
+	b, err := c.secrets.Get(secretSession)
+	if err != nil {
+		t.Fatalf("reading cached session: %v", err)
+	}
+	var sr SessionResponse
+	if err = json.Unmarshal(b, &sr); err != nil || sr.Session.ID() != "sess-2" {
+		t.Fatalf("cached session = %s (%v), want sess-2", b, err)
+	}
+}
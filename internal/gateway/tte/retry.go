@@ -0,0 +1,122 @@
+package tte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried.
+type RetryPolicy struct {
+	// MaxRetries is the number of attempts made after the first one.
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles per attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps both the backoff and any Retry-After the server asks for.
+	MaxDelay time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 4,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
+// StatusError is returned when tabletop.events answers with a non-2xx
+// status and no api error envelope.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (se *StatusError) Error() string {
+	return fmt.Sprintf("unexpected http status %s", se.Status)
+}
+
+// Is matches the sentinel error for the http status, e.g.
+// errors.Is(err, ErrRateLimited) for a 429.
+func (se *StatusError) Is(target error) bool {
+	return target == sentinelForCode(se.StatusCode)
+}
+
+// backoff returns the exponential delay before retry number attempt
+// (starting at 0) with jitter over the upper half of the interval.
+func (rp RetryPolicy) backoff(attempt int) time.Duration {
+	d := rp.BaseDelay << attempt
+	if d <= 0 || (rp.MaxDelay > 0 && d > rp.MaxDelay) {
+		d = rp.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// delay picks the wait before the next attempt, honoring Retry-After.
+func (rp RetryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	d := rp.backoff(attempt)
+	if resp == nil {
+		return d
+	}
+	if ra, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+		d = ra
+	}
+	if rp.MaxDelay > 0 && d > rp.MaxDelay {
+		d = rp.MaxDelay
+	}
+	return d
+}
+
+func retryAfter(v string) (time.Duration, bool) {
+	if len(v) == 0 {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// idempotent reports whether sending a request with method twice has the
+// same effect as sending it once, which makes it safe to retry.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func retryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrOffline) {
+		return false
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
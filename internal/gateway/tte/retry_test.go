@@ -0,0 +1,119 @@
+package tte
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fastRetries() ClientOption {
+	return WithRetryPolicy(RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})
+}
+
+func TestSendRetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = w.Write([]byte(`{"result":{}}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(quietLog, NewMemoryDB(), "test-key", WithBaseURL(srv.URL), fastRetries())
+	b, err := c.httpGet(context.Background(), "/api/user", nil, nil)
+	if err != nil {
+		t.Fatalf("httpGet: %v", err)
+	}
+	if string(b) != `{"result":{}}` {
+		t.Fatalf("body = %q", b)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("server saw %d calls, want 3", n)
+	}
+}
+
+func TestSendGivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	c := NewClient(quietLog, NewMemoryDB(), "test-key", WithBaseURL(srv.URL), fastRetries())
+	_, err := c.httpGet(context.Background(), "/api/user", nil, nil)
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusBadGateway {
+		t.Fatalf("err = %v, want a 502 StatusError", err)
+	}
+	if n := calls.Load(); n != 4 {
+		t.Fatalf("server saw %d calls, want 4", n)
+	}
+}
+
+func TestSendPassesApiErrorsThrough(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"code":441,"message":"session expired"}}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(quietLog, NewMemoryDB(), "test-key", WithBaseURL(srv.URL), fastRetries())
+	if _, err := c.httpGet(context.Background(), "/api/user", nil, nil); err != nil {
+		t.Fatalf("httpGet: %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("server saw %d calls, want 1", n)
+	}
+}
+
+func TestSendOnlyRetriesPostsThatWereRateLimited(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = w.Write([]byte(`{"result":{"id":"sess-1"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(quietLog, NewMemoryDB(), "test-key", WithBaseURL(srv.URL), fastRetries())
+	_, err := c.NewSession("gm", "secret")
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want a 503 StatusError", err)
+	}
+	// the 429 was retried, the 503 may have logged in and was not
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("server saw %d calls, want 2", n)
+	}
+}
+
+func TestTokenBucketLimitsRate(t *testing.T) {
+	tb := newTokenBucket(100, 2)
+	if d := tb.reserve(); d != 0 {
+		t.Fatalf("first token delayed %s", d)
+	}
+	if d := tb.reserve(); d != 0 {
+		t.Fatalf("burst token delayed %s", d)
+	}
+	if d := tb.reserve(); d <= 0 || d > 20*time.Millisecond {
+		t.Fatalf("third token delay = %s, want about 10ms", d)
+	}
+}
@@ -0,0 +1,91 @@
+package tte
+
+import "sort"
+
+// Conflict is a group of events that overlap, directly or through a chain
+// of overlapping events, and the time slot they span together.
+type Conflict struct {
+	Slot   EventTime
+	Events []ConventionEvent
+}
+
+// Conflicts are ordered by the start of their slot.
+type Conflicts []Conflict
+
+// Has reports whether e is part of any conflict.
+func (cz Conflicts) Has(e ConventionEvent) bool {
+	for _, c := range cz {
+		for _, ce := range c.Events {
+			if ce.ID == e.ID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// With returns the events e overlaps according to tt. Events in the same
+// conflict may only overlap through others, so each is checked against e.
+func (cz Conflicts) With(tt Timetable, e ConventionEvent) (others []ConventionEvent) {
+	t, ok := tt.Get(e)
+	if !ok {
+		return nil
+	}
+	for _, c := range cz {
+		var in bool
+		for _, ce := range c.Events {
+			in = in || ce.ID == e.ID
+		}
+		if !in {
+			continue
+		}
+		for _, ce := range c.Events {
+			if ct, ok := tt.Get(ce); ok && ce.ID != e.ID && t.Overlaps(ct) {
+				others = append(others, ce)
+			}
+		}
+	}
+	return others
+}
+
+// Exempt reports whether e may overlap other events, e.g. a drop-in open
+// gaming area.
+func (e ConventionEvent) Exempt() bool {
+	return e.AllowScheduleConflicts != 0
+}
+
+// Conflicts finds the events of ez that overlap, grouped by time slot.
+// Exempt events and events without a time never conflict.
+func (tt Timetable) Conflicts(ez []ConventionEvent) (cz Conflicts) {
+	var timed []ConventionEvent
+	for _, e := range ez {
+		if _, ok := tt[e.ID]; ok && !e.Exempt() {
+			timed = append(timed, e)
+		}
+	}
+	tt.Sort(timed)
+
+	var current Conflict
+	flush := func() {
+		if len(current.Events) > 1 {
+			cz = append(cz, current)
+		}
+		current = Conflict{}
+	}
+	for _, e := range timed {
+		t := tt[e.ID]
+		if len(current.Events) > 0 && !t.Start.Before(current.Slot.End) {
+			flush()
+		}
+		if len(current.Events) == 0 {
+			current.Slot = t
+		}
+		if t.End.After(current.Slot.End) {
+			current.Slot.End = t.End
+		}
+		current.Events = append(current.Events, e)
+	}
+	flush()
+	sort.SliceStable(cz, func(i, j int) bool { return cz[i].Slot.Start.Before(cz[j].Slot.Start) })
+	return cz
+}
@@ -0,0 +1,51 @@
+package tte
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConflictsGroupOverlapsBySlot(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2025, 8, 1, h, 0, 0, 0, time.UTC) }
+	tt := Timetable{
+		"a":      {Start: at(9), End: at(11)},
+		"b":      {Start: at(10), End: at(12)},
+		"c":      {Start: at(11), End: at(14)},
+		"d":      {Start: at(14), End: at(15)},
+		"e":      {Start: at(16), End: at(18)},
+		"f":      {Start: at(17), End: at(18)},
+		"exempt": {Start: at(14), End: at(15)},
+	}
+	ez := []ConventionEvent{
+		{ID: "f", Name: "F"}, {ID: "e", Name: "E"}, {ID: "d", Name: "D"},
+		{ID: "c", Name: "C"}, {ID: "b", Name: "B"}, {ID: "a", Name: "A"},
+		{ID: "exempt", Name: "Open Gaming", AllowScheduleConflicts: 1},
+		{ID: "untimed", Name: "TBD"},
+	}
+
+	cz := tt.Conflicts(ez)
+	if len(cz) != 2 {
+		t.Fatalf("conflicts = %+v", cz)
+	}
+	// a overlaps b and b overlaps c, so all three share a 9-14 slot; d starts
+	// as c ends
+	if len(cz[0].Events) != 3 || !cz[0].Slot.Start.Equal(at(9)) || !cz[0].Slot.End.Equal(at(14)) {
+		t.Errorf("first slot = %+v", cz[0])
+	}
+	if len(cz[1].Events) != 2 || cz[1].Events[0].ID != "e" {
+		t.Errorf("second slot = %+v", cz[1])
+	}
+	if cz.Has(ConventionEvent{ID: "d"}) || cz.Has(ConventionEvent{ID: "exempt"}) {
+		t.Error("d or the exempt event conflicts")
+	}
+	if with := cz.With(tt, ConventionEvent{ID: "f"}); len(with) != 1 || with[0].ID != "e" {
+		t.Errorf("f conflicts with %+v", with)
+	}
+	// a and c share a slot through b but do not overlap each other
+	if with := cz.With(tt, ConventionEvent{ID: "a"}); len(with) != 1 || with[0].ID != "b" {
+		t.Errorf("a conflicts with %+v", with)
+	}
+	if with := cz.With(tt, ConventionEvent{ID: "b"}); len(with) != 2 {
+		t.Errorf("b conflicts with %+v", with)
+	}
+}
@@ -0,0 +1,201 @@
+package tte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SoldOut reports whether every ticket of e has been taken.
+func (e ConventionEvent) SoldOut() bool {
+	return e.MaxTickets > 0 && e.AvailableQuantity <= 0
+}
+
+// NearlyFull reports whether e has some, but at most seats, tickets left.
+func (e ConventionEvent) NearlyFull(seats int) bool {
+	return e.AvailableQuantity > 0 && e.AvailableQuantity <= seats
+}
+
+// Seats shows the tickets of e, e.g. "2 of 6 left, 3 waiting".
+func (e ConventionEvent) Seats() string {
+	if e.MaxTickets == 0 {
+		return ""
+	}
+	s := fmt.Sprintf("%d of %d left", max(e.AvailableQuantity, 0), e.MaxTickets)
+	if e.SoldOut() {
+		s = fmt.Sprintf("sold out (%d)", e.SoldCount)
+	}
+	if e.UnreservedQuantity > 0 && e.UnreservedQuantity != e.AvailableQuantity {
+		s += fmt.Sprintf(", %d unreserved", e.UnreservedQuantity)
+	}
+	if e.WaitCount > 0 {
+		s += fmt.Sprintf(", %d waiting", e.WaitCount)
+	}
+	return s
+}
+
+type EventResponse struct {
+	Result ConventionEvent `json:"result"`
+	Err    *ApiError       `json:"error"`
+}
+
+func (s Session) GetEvent(id string) (e ConventionEvent, err error) {
+	return s.GetEventContext(context.Background(), id)
+}
+
+// GetEventContext fetches the current state of the event with id. Seat
+// counts change too often to cache, so it never reads the cache.
+func (s Session) GetEventContext(ctx context.Context, id string) (e ConventionEvent, err error) {
+	var resp EventResponse
+	var b []byte
+	if b, err = s.get(ctx, "/api/event/"+id, map[string]string{"_include_relationships": "1"}); err != nil {
+		return e, err
+	}
+	if err = decodeResponse(b, &resp); err != nil {
+		return e, err
+	}
+	if resp.Err != nil {
+		return e, resp.Err
+	}
+	return resp.Result, nil
+}
+
+type SeatAlertKind string
+
+const (
+	// SeatsOpened is raised when a sold out event has tickets again.
+	SeatsOpened SeatAlertKind = "seats opened"
+	// SeatsRunningOut is raised when an event becomes nearly full.
+	SeatsRunningOut SeatAlertKind = "nearly full"
+)
+
+// SeatAlert is a change in the tickets of a watched event worth acting on.
+type SeatAlert struct {
+	Kind  SeatAlertKind
+	Event ConventionEvent
+	// From and To are the tickets left before and after.
+	From int
+	To   int
+}
+
+func (a SeatAlert) String() string {
+	return fmt.Sprintf("%s: #%d %s (%s)", a.Kind, a.Event.EventNumber, a.Event.Name, a.Event.Seats())
+}
+
+// CheckSeats compares the events of before and after by id and alerts when
+// one that was sold out has tickets again, or when one becomes nearly full,
+// i.e. has at most nearlyFull tickets left. Events missing from before are
+// not alerted on.
+func CheckSeats(before []ConventionEvent, after []ConventionEvent, nearlyFull int) (alerts []SeatAlert) {
+	previous := make(map[string]ConventionEvent, len(before))
+	for _, e := range before {
+		previous[e.ID] = e
+	}
+	for _, e := range after {
+		o, ok := previous[e.ID]
+		if !ok || e.IsCancelled != 0 {
+			continue
+		}
+		alert := SeatAlert{Event: e, From: o.AvailableQuantity, To: e.AvailableQuantity}
+		switch {
+		case o.SoldOut() && !e.SoldOut():
+			alert.Kind = SeatsOpened
+		case !o.NearlyFull(nearlyFull) && o.AvailableQuantity > 0 && e.NearlyFull(nearlyFull):
+			alert.Kind = SeatsRunningOut
+		default:
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+// Notifier raises seat alerts, e.g. on the desktop.
+type Notifier interface {
+	Notify(ctx context.Context, a SeatAlert) error
+}
+
+// NotifierFunc adapts a function to a Notifier.
+type NotifierFunc func(ctx context.Context, a SeatAlert) error
+
+func (f NotifierFunc) Notify(ctx context.Context, a SeatAlert) error {
+	return f(ctx, a)
+}
+
+// Notifiers raise each alert through every one of them.
+type Notifiers []Notifier
+
+func (nz Notifiers) Notify(ctx context.Context, a SeatAlert) error {
+	var errs []error
+	for _, n := range nz {
+		errs = append(errs, n.Notify(ctx, a))
+	}
+	return errors.Join(errs...)
+}
+
+// MinWatchInterval keeps WatchSeats from polling tabletop.events too often.
+const MinWatchInterval = time.Minute
+
+// SeatWatch configures WatchSeats.
+type SeatWatch struct {
+	// Interval is the time between polls, at least MinWatchInterval.
+	Interval time.Duration
+	// NearlyFull is the number of tickets left at which an event is
+	// nearly full.
+	NearlyFull int
+	Notifier   Notifier
+}
+
+// PollSeatsContext fetches the current state of each event of ez. An event
+// that cannot be fetched, e.g. because tabletop.events is unreachable, rate
+// limiting or no longer has it, is logged and kept as it was. It only fails
+// when ctx is done.
+func (s Session) PollSeatsContext(ctx context.Context, ez []ConventionEvent) (current []ConventionEvent, err error) {
+	current = make([]ConventionEvent, len(ez))
+	for i, e := range ez {
+		current[i] = e
+		fetched, err := s.GetEventContext(ctx, e.ID)
+		if ctx.Err() != nil {
+			return current, ctx.Err()
+		}
+		if err != nil {
+			s.log.Warn("unable to poll event", "event", e.ViewURI, "error", err)
+			continue
+		}
+		current[i] = fetched
+	}
+	return current, nil
+}
+
+// WatchSeatsContext polls the events of ez every w.Interval until ctx is
+// done and raises an alert through w.Notifier for each change CheckSeats
+// finds. ez is the baseline the first poll is compared with, so it should
+// be current, e.g. from PollSeatsContext. Events that cannot be polled keep
+// their last known state and are tried again on the next poll.
+func (s Session) WatchSeatsContext(ctx context.Context, ez []ConventionEvent, w SeatWatch) error {
+	interval := max(w.Interval, MinWatchInterval)
+	last := append([]ConventionEvent(nil), ez...)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		current, err := s.PollSeatsContext(ctx, last)
+		if err != nil {
+			return err
+		}
+		s.log.Debug("polled seats", "events", len(current), "next", interval)
+
+		for _, a := range CheckSeats(last, current, w.NearlyFull) {
+			if err := w.Notifier.Notify(ctx, a); err != nil {
+				s.log.Error("unable to raise seat alert", "alert", a.String(), "error", err)
+			}
+		}
+		last = current
+	}
+}
@@ -0,0 +1,85 @@
+package tte
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckSeatsAlertsOnOpenedAndNearlyFull(t *testing.T) {
+	before := []ConventionEvent{
+		{ID: "sold", Name: "Gloomhaven", MaxTickets: 4, AvailableQuantity: 0, SoldCount: 4},
+		{ID: "filling", Name: "Catan", MaxTickets: 6, AvailableQuantity: 4},
+		{ID: "full", Name: "Azul", MaxTickets: 4, AvailableQuantity: 1},
+		{ID: "cancelled", Name: "Root", MaxTickets: 4},
+	}
+	after := []ConventionEvent{
+		{ID: "sold", Name: "Gloomhaven", MaxTickets: 4, AvailableQuantity: 1, SoldCount: 3, WaitCount: 2},
+		{ID: "filling", Name: "Catan", MaxTickets: 6, AvailableQuantity: 2},
+		// already nearly full before, so no new alert
+		{ID: "full", Name: "Azul", MaxTickets: 4, AvailableQuantity: 1},
+		{ID: "cancelled", Name: "Root", MaxTickets: 4, AvailableQuantity: 4, IsCancelled: 1},
+		{ID: "new", Name: "Wingspan", MaxTickets: 4, AvailableQuantity: 1},
+	}
+
+	alerts := CheckSeats(before, after, 2)
+	if len(alerts) != 2 {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+	if a := alerts[0]; a.Kind != SeatsOpened || a.Event.ID != "sold" || a.From != 0 || a.To != 1 {
+		t.Errorf("first alert = %+v", a)
+	}
+	if a := alerts[1]; a.Kind != SeatsRunningOut || a.Event.ID != "filling" {
+		t.Errorf("second alert = %+v", a)
+	}
+	if got, want := alerts[0].Event.Seats(), "1 of 4 left, 2 waiting"; got != want {
+		t.Errorf("seats = %q, want %q", got, want)
+	}
+	if got, want := before[0].Seats(), "sold out (4)"; got != want {
+		t.Errorf("seats = %q, want %q", got, want)
+	}
+}
+
+func TestGetEventFetchesCurrentSeats(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/api/event/ev-1" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": ConventionEvent{ID: "ev-1", MaxTickets: 6, AvailableQuantity: 6 - calls}})
+	}))
+	t.Cleanup(srv.Close)
+	s := Session{id: "sess-1", client: NewClient(quietLog, NewMemoryDB(), "test-key", WithBaseURL(srv.URL)), log: quietLog}
+
+	for want := 5; want >= 4; want-- {
+		e, err := s.GetEvent("ev-1")
+		if err != nil || e.AvailableQuantity != want {
+			t.Fatalf("GetEvent = %+v, %v; want %d seats", e, err, want)
+		}
+	}
+}
+
+func TestPollSeatsKeepsEventsThatCannotBeFetched(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/event/ev-1" {
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 404, "message": "not found"}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": ConventionEvent{ID: "ev-1", MaxTickets: 6, AvailableQuantity: 1}})
+	}))
+	t.Cleanup(srv.Close)
+	s := Session{id: "sess-1", client: NewClient(quietLog, NewMemoryDB(), "test-key", WithBaseURL(srv.URL)), log: quietLog}
+
+	ez := []ConventionEvent{{ID: "ev-1", AvailableQuantity: 5}, {ID: "gone", AvailableQuantity: 3}}
+	current, err := s.PollSeatsContext(context.Background(), ez)
+	if err != nil || len(current) != 2 {
+		t.Fatalf("PollSeats = %+v, %v", current, err)
+	}
+	if current[0].AvailableQuantity != 1 || current[1].AvailableQuantity != 3 {
+		t.Errorf("polled = %+v", current)
+	}
+}
@@ -0,0 +1,204 @@
+package tte
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// SecretStore keeps credentials and sessions.
+type SecretStore interface {
+	Put(name string, secret []byte) error
+	Get(name string) ([]byte, error)
+	Delete(name string) error
+	// Age reports how long ago the secret was last written.
+	Age(name string) (time.Duration, error)
+}
+
+// names of the secrets a Client keeps
+const (
+	secretAPIKey   = "apikey"
+	secretSession  = "session"
+	secretUserName = "username"
+)
+
+const secretsKind = "secrets"
+
+var (
+	_ SecretStore = PlainSecretStore{}
+	_ SecretStore = EncryptedSecretStore{}
+)
+
+// PlainSecretStore keeps secrets unencrypted in files only the owner can
+// read.
+type PlainSecretStore struct {
+	db DB
+}
+
+func NewPlainSecretStore(db DB) PlainSecretStore {
+	return PlainSecretStore{db: db}
+}
+
+func (ps PlainSecretStore) Put(name string, secret []byte) error {
+	return ps.db.Store(name, secretsKind, "secret", secret)
+}
+
+func (ps PlainSecretStore) Get(name string) ([]byte, error) {
+	return ps.db.Read(name, secretsKind, "secret")
+}
+
+func (ps PlainSecretStore) Delete(name string) error {
+	return ps.db.Delete(name, secretsKind, "secret")
+}
+
+func (ps PlainSecretStore) Age(name string) (time.Duration, error) {
+	return ps.db.CacheAge(name, secretsKind, "secret")
+}
+
+// ErrBadPassphrase is returned when an encrypted secret cannot be opened
+// with the passphrase the store was created with.
+var ErrBadPassphrase = errors.New("wrong passphrase for encrypted secrets")
+
+const (
+	encryptedSecretMagic = "tte1"
+	pbkdf2Iterations     = 600_000
+	saltSize             = 16
+)
+
+// EncryptedSecretStore seals secrets with AES-256-GCM under a key derived
+// from a passphrase with PBKDF2. The salt is generated once per DB and kept
+// beside the secrets.
+type EncryptedSecretStore struct {
+	db   DB
+	aead cipher.AEAD
+}
+
+func NewEncryptedSecretStore(db DB, passphrase []byte) (es EncryptedSecretStore, err error) {
+	if len(passphrase) == 0 {
+		return es, fmt.Errorf("an empty passphrase cannot encrypt secrets")
+	}
+	var salt []byte
+	// a new salt would orphan every secret sealed with the old one, so it
+	// is only made when there is none
+	salt, err = db.Read("salt", secretsKind, "bin")
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		salt = make([]byte, saltSize)
+		_, _ = rand.Read(salt)
+		if err = db.Store("salt", secretsKind, "bin", salt); err != nil {
+			return es, err
+		}
+	case err != nil:
+		return es, fmt.Errorf("unable to read the secrets salt: %w", err)
+	case len(salt) != saltSize:
+		return es, fmt.Errorf("secrets salt is %d bytes, want %d: it is damaged", len(salt), saltSize)
+	}
+
+	var key []byte
+	key, err = pbkdf2.Key(sha256.New, string(passphrase), salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return es, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return es, err
+	}
+	es.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return es, err
+	}
+	es.db = db
+	return es, nil
+}
+
+// HasEncryptedSecrets reports whether db already holds secrets sealed by an
+// EncryptedSecretStore, i.e. whether a passphrase is needed to read them.
+func HasEncryptedSecrets(db DB) bool {
+	_, err := db.CacheAge("salt", secretsKind, "bin")
+	return err == nil
+}
+
+func (es EncryptedSecretStore) Put(name string, secret []byte) error {
+	nonce := make([]byte, es.aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	sealed := append([]byte(encryptedSecretMagic), nonce...)
+	sealed = es.aead.Seal(sealed, nonce, secret, []byte(name))
+	return es.db.Store(name, secretsKind, "enc", sealed)
+}
+
+func (es EncryptedSecretStore) Get(name string) ([]byte, error) {
+	sealed, err := es.db.Read(name, secretsKind, "enc")
+	if err != nil {
+		return nil, err
+	}
+	header := len(encryptedSecretMagic) + es.aead.NonceSize()
+	if len(sealed) < header || !bytes.HasPrefix(sealed, []byte(encryptedSecretMagic)) {
+		return nil, fmt.Errorf("secret %s is not an encrypted record", name)
+	}
+	nonce := sealed[len(encryptedSecretMagic):header]
+	secret, err := es.aead.Open(nil, nonce, sealed[header:], []byte(name))
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return secret, nil
+}
+
+func (es EncryptedSecretStore) Delete(name string) error {
+	return es.db.Delete(name, secretsKind, "enc")
+}
+
+func (es EncryptedSecretStore) Age(name string) (time.Duration, error) {
+	return es.db.CacheAge(name, secretsKind, "enc")
+}
+
+// legacySecrets lists where secrets were kept in plaintext before there was
+// a SecretStore.
+var legacySecrets = []struct {
+	name     string
+	id       string
+	kind     string
+	dataType string
+}{
+	{secretAPIKey, "apikey", "client", "txt"},
+	{secretSession, "session", "session", "json"},
+	{secretUserName, "username", "session", "txt"},
+}
+
+// MigrateSecrets moves plaintext secrets left by older versions of buddy
+// (and unencrypted secrets, when to is encrypted) into to, returning the
+// names of the secrets it moved.
+func MigrateSecrets(db DB, to SecretStore) (migrated []string, err error) {
+	plain := NewPlainSecretStore(db)
+	for _, ls := range legacySecrets {
+		var b []byte
+		if b, err = db.Read(ls.id, ls.kind, ls.dataType); err == nil {
+			if err = to.Put(ls.name, b); err != nil {
+				return migrated, err
+			}
+			if err = db.Delete(ls.id, ls.kind, ls.dataType); err != nil {
+				return migrated, err
+			}
+			migrated = append(migrated, ls.name)
+		}
+		if _, ok := to.(PlainSecretStore); ok {
+			continue
+		}
+		if b, err = plain.Get(ls.name); err == nil {
+			if err = to.Put(ls.name, b); err != nil {
+				return migrated, err
+			}
+			if err = plain.Delete(ls.name); err != nil {
+				return migrated, err
+			}
+			migrated = append(migrated, ls.name)
+		}
+	}
+	return migrated, nil
+}
@@ -0,0 +1,77 @@
+package tte
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestEncryptedSecretStore(t *testing.T) {
+	db := NewMemoryDB()
+
+	es, err := NewEncryptedSecretStore(db, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("NewEncryptedSecretStore: %v", err)
+	}
+	if err = es.Put(secretAPIKey, []byte("test-key")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	sealed, err := db.Read(secretAPIKey, secretsKind, "enc")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(sealed) == "test-key" || len(sealed) == 0 {
+		t.Fatalf("secret stored in plaintext: %q", sealed)
+	}
+	b, err := es.Get(secretAPIKey)
+	if err != nil || string(b) != "test-key" {
+		t.Fatalf("Get = %q, %v", b, err)
+	}
+
+	wrong, err := NewEncryptedSecretStore(db, []byte("battery staple"))
+	if err != nil {
+		t.Fatalf("NewEncryptedSecretStore: %v", err)
+	}
+	if _, err = wrong.Get(secretAPIKey); !errors.Is(err, ErrBadPassphrase) {
+		t.Fatalf("err = %v, want ErrBadPassphrase", err)
+	}
+}
+
+func TestEncryptedSecretStoreKeepsADamagedSalt(t *testing.T) {
+	db := NewMemoryDB()
+	if _, err := NewEncryptedSecretStore(db, []byte("correct horse")); err != nil {
+		t.Fatalf("NewEncryptedSecretStore: %v", err)
+	}
+	_ = db.Store("salt", secretsKind, "bin", []byte("short"))
+	if _, err := NewEncryptedSecretStore(db, []byte("correct horse")); err == nil {
+		t.Fatal("a damaged salt was accepted")
+	}
+	if salt, _ := db.Read("salt", secretsKind, "bin"); string(salt) != "short" {
+		t.Fatalf("salt replaced with %x", salt)
+	}
+}
+
+func TestMigrateSecretsFromPlaintext(t *testing.T) {
+	db := NewFileDB(quietLog, t.TempDir())
+	if err := db.Store("apikey", "client", "txt", []byte("legacy-key")); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	c, err := RestoreClient(quietLog, db)
+	if err != nil {
+		t.Fatalf("RestoreClient: %v", err)
+	}
+	if c.key != "legacy-key" {
+		t.Fatalf("key = %q, want legacy-key", c.key)
+	}
+	if _, err = db.Read("apikey", "client", "txt"); err == nil {
+		t.Fatal("plaintext api key left behind after migration")
+	}
+	fi, err := os.Stat(db.itemPath(secretAPIKey, secretsKind, "secret"))
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if mode := fi.Mode().Perm(); mode != 0600 {
+		t.Fatalf("secret file mode = %o, want 600", mode)
+	}
+}
@@ -1,18 +1,23 @@
 package tte
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/dan-frohlich/tabetopevents/internal/logging"
 )
 
+// Session is a tabletop.events login. Its id changes when it is renewed, so
+// it is only read through ID.
 type Session struct {
-	ID       string `json:"id"`
-	UID      string `json:"user_id"`
+	id       string
+	uid      string
 	userName string
 	client   Client
 	log      logging.Logger
+	auth     *sessionAuth
 }
 
 type ApiError struct {
@@ -32,6 +37,15 @@ func (ae *ApiError) Error() string {
 	return ae.String()
 }
 
+// Is matches the sentinel error for the api error code, e.g.
+// errors.Is(err, ErrSessionExpired).
+func (ae *ApiError) Is(target error) bool {
+	if ae == nil {
+		return false
+	}
+	return target == sentinelForCode(ae.Code)
+}
+
 type UserRespose struct {
 	Result Users     `json:"result"`
 	Err    *ApiError `json:"error"`
@@ -45,33 +59,105 @@ type Users struct {
 type User map[string]any
 
 func (s Session) TestConnection() (err error) {
+	return s.TestConnectionContext(context.Background())
+}
+
+// TestConnectionContext verifies the session is still accepted by
+// tabletop.events.
+func (s Session) TestConnectionContext(ctx context.Context) (err error) {
 	params := map[string]string{
-		"session_id": s.ID,
+		"session_id": s.ID(),
 	}
 	var b []byte
-	b, err = s.client.httpGet("/api/user", params, nil)
+	b, err = s.client.httpGet(ctx, "/api/user", params, nil)
 	if err != nil {
 		return err
 	}
 
 	var user UserRespose
-	err = json.Unmarshal(b, &user)
+	err = decodeResponse(b, &user)
 
-	const (
-		badSessionCode    = 401
-		noSessionCode     = 441
-		adminRequiredCode = 450
-	)
-	if user.Err != nil {
-		switch user.Err.Code {
-		case badSessionCode, noSessionCode:
-			return user.Err
-		case adminRequiredCode: //[expetcted] "You must be an admin to do that."
-			fallthrough
-		default:
-		}
+	// an admin required error is expected: "You must be an admin to do that."
+	if user.Err != nil && (errors.Is(user.Err, ErrSessionExpired) || errors.Is(user.Err, ErrAuthRequired)) {
+		return user.Err
 	}
 
 	return err
 
 }
+
+// sessionJSON is how tabletop.events and the secret store write a session.
+type sessionJSON struct {
+	ID  string `json:"id"`
+	UID string `json:"user_id"`
+}
+
+func (s Session) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sessionJSON{ID: s.ID(), UID: s.UID()})
+}
+
+func (s *Session) UnmarshalJSON(b []byte) error {
+	var sj sessionJSON
+	if err := json.Unmarshal(b, &sj); err != nil {
+		return err
+	}
+	s.id, s.uid = sj.ID, sj.UID
+	return nil
+}
+
+// ID returns the id of the session, following any renewals.
+func (s Session) ID() string {
+	if s.auth == nil {
+		return s.id
+	}
+	s.auth.mu.Lock()
+	defer s.auth.mu.Unlock()
+	return s.auth.id
+}
+
+// UID returns the id of the session's user, following any renewals.
+func (s Session) UID() string {
+	if s.auth == nil {
+		return s.uid
+	}
+	s.auth.mu.Lock()
+	defer s.auth.mu.Unlock()
+	return s.auth.uid
+}
+
+// UserName is the login the session was created with, when known.
+func (s Session) UserName() string {
+	return s.userName
+}
+
+func (s Session) Logout(forgetAPIKey bool) error {
+	return s.LogoutContext(context.Background(), forgetAPIKey)
+}
+
+// LogoutContext ends the session on tabletop.events and clears the cached
+// session, and the cached api key when forgetAPIKey is set. The local cache
+// is cleared even when tabletop.events no longer knows the session.
+func (s Session) LogoutContext(ctx context.Context, forgetAPIKey bool) (err error) {
+	id := s.ID()
+	var b []byte
+	b, err = s.client.httpDelete(ctx, "/api/session/"+id, map[string]string{"session_id": id})
+	if err == nil {
+		var resp SessionResponse
+		if err = decodeResponse(b, &resp); err == nil && resp.Err != nil && !isBadSession(b) && !errors.Is(resp.Err, ErrNotFound) {
+			err = resp.Err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	s.log.Info("logged out", "session_id", id)
+
+	err = errors.Join(
+		s.client.secrets.Delete(secretSession),
+		s.client.secrets.Delete(secretUserName),
+	)
+	if forgetAPIKey {
+		err = errors.Join(err, s.client.ForgetAPIKey())
+	}
+	return err
+}
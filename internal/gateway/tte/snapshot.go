@@ -0,0 +1,91 @@
+package tte
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"time"
+)
+
+// CacheSnapshot is the id of the dated snapshots of a convention's events.
+// Each fetch is kept under its own kind below the convention, so the events
+// cache can be refreshed without losing what it held before.
+const CacheSnapshot = "snapshot"
+
+const (
+	snapshotsKind  = "snapshots"
+	snapshotLayout = "20060102-150405.000"
+	// maxSnapshots is how many snapshots of a convention are kept.
+	maxSnapshots = 20
+)
+
+func snapshotsOf(con Convention) string {
+	return path.Join(cleanKind(con.ViewURI), snapshotsKind)
+}
+
+func snapshotKind(con Convention, taken time.Time) string {
+	return path.Join(snapshotsOf(con), FormatSnapshotTime(taken))
+}
+
+// StoreSnapshot stores ez as the snapshot of con taken now and drops the
+// oldest snapshots beyond the ones kept.
+func StoreSnapshot(db DB, con Convention, source string, ez []ConventionEvent) (taken time.Time, err error) {
+	taken = time.Now().UTC().Truncate(time.Millisecond)
+	if err = storeRecord(db, CacheSnapshot, snapshotKind(con, taken), source, ConventionEvents{Items: ez}); err != nil {
+		return taken, err
+	}
+	var all []time.Time
+	if all, err = Snapshots(db, con); err != nil {
+		return taken, err
+	}
+	for len(all) > maxSnapshots {
+		if err = db.Delete(CacheSnapshot, snapshotKind(con, all[0]), "json"); err != nil {
+			return taken, err
+		}
+		all = all[1:]
+	}
+	return taken, nil
+}
+
+// Snapshots returns when each stored snapshot of con was taken, oldest
+// first.
+func Snapshots(db DB, con Convention) (taken []time.Time, err error) {
+	var keys []Key
+	if keys, err = db.List(snapshotsOf(con)); err != nil {
+		return taken, err
+	}
+	for _, k := range keys {
+		if k.ID != CacheSnapshot || path.Dir(k.Kind) != snapshotsOf(con) {
+			continue
+		}
+		t, e := time.Parse(snapshotLayout, path.Base(k.Kind))
+		if e != nil {
+			continue
+		}
+		taken = append(taken, t)
+	}
+	sort.Slice(taken, func(i, j int) bool { return taken[i].Before(taken[j]) })
+	return taken, nil
+}
+
+// ReadSnapshot returns the events of the snapshot of con taken at taken.
+func ReadSnapshot(db DB, con Convention, taken time.Time) (ez []ConventionEvent, err error) {
+	var c ConventionEvents
+	if _, err = readRecord(db, CacheSnapshot, snapshotKind(con, taken), &c); err != nil {
+		return ez, err
+	}
+	return c.Items, nil
+}
+
+// ParseSnapshotTime parses a snapshot time as FormatSnapshotTime prints it.
+func ParseSnapshotTime(s string) (taken time.Time, err error) {
+	if taken, err = time.Parse(snapshotLayout, s); err != nil {
+		return taken, fmt.Errorf("invalid snapshot time %q, want e.g. %s", s, FormatSnapshotTime(time.Now()))
+	}
+	return taken, nil
+}
+
+// FormatSnapshotTime names a snapshot by when it was taken.
+func FormatSnapshotTime(taken time.Time) string {
+	return taken.UTC().Format(snapshotLayout)
+}